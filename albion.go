@@ -1,81 +1,701 @@
 package main
 
 import (
+	"M00DSWINGS/audit"
+	"M00DSWINGS/identity"
+	"M00DSWINGS/items"
+	"M00DSWINGS/lootchest"
+	"M00DSWINGS/lootsplit"
+	"M00DSWINGS/pricing"
+	"M00DSWINGS/protocol"
+	"M00DSWINGS/storage"
 	"context"
+	"errors"
 	"github.com/google/uuid"
+	"log"
+	"math"
 	"sync"
+	"time"
 )
 
+var (
+	InvalidCharacter = errors.New("Player information is incorrect")
+	NotInParty       = errors.New("Not in a party")
+)
+
+type Character struct {
+	ID       uuid.UUID
+	Name     string
+	Guild    string
+	Alliance string
+}
+
+// GameDataManager Local state of the session, every party and character event goes through it before it is
+// forwarded so the server can be resynced from it after a reconnect. Characters are registered with the identity
+// registry it was created with. CurrentUser and CurrentParty are guarded by mx, use the methods instead of
+// touching them directly. CurrentCluster is the zone the player is in, every record is stamped with it.
 type GameDataManager struct {
 	Parties    *sync.Map
 	Characters *sync.Map
 
-	CurrentUser  uuid.UUID
-	CurrentParty *Party
+	mx             *sync.Mutex
+	identities     *identity.Registry
+	store          storage.Store
+	items          *items.Database
+	prices         pricing.Provider
+	market         *pricing.Cache
+	CurrentUser    uuid.UUID
+	CurrentParty   *Party
+	CurrentCluster string
 }
 
-func NewGameDataManager() *GameDataManager {
+func NewGameDataManager(identities *identity.Registry) *GameDataManager {
 	return &GameDataManager{
 		Parties:     new(sync.Map),
 		Characters:  new(sync.Map),
+		mx:          new(sync.Mutex),
+		identities:  identities,
 		CurrentUser: uuid.Nil,
 	}
 }
 
-func (m *GameDataManager) CreateNewChar(playerUuid uuid.UUID, playerName string) {
-	if playerName == "" || playerUuid == uuid.Nil {
-		panic("Player information is incorrect")
+// RegisterStore Persists everything the manager learns from now on, known characters are loaded from the store
+func (m *GameDataManager) RegisterStore(s storage.Store) error {
+	characters, err := s.Characters()
+	if err != nil {
+		return err
 	}
 
-	_, ok := m.Characters.Load(playerUuid)
-	if ok {
-		return
+	for _, c := range characters {
+		m.Characters.Store(c.ID, &Character{
+			ID:       c.ID,
+			Name:     c.Name,
+			Guild:    c.Guild,
+			Alliance: c.Alliance,
+		})
+		m.identities.Know(c.ID, c.Name)
 	}
 
-	m.Characters.Store(playerUuid, playerName)
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.store = s
+
+	return nil
 }
 
-func (m *GameDataManager) Initialize(playerUuid uuid.UUID, playerName string) {
+// RegisterItems Resolves item indices of the loot the manager persists
+func (m *GameDataManager) RegisterItems(db *items.Database) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.items = db
+}
+
+// RegisterPrices Values loot with prices, the estimated market values the game sends are stored in market
+func (m *GameDataManager) RegisterPrices(prices pricing.Provider, market *pricing.Cache) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.prices = prices
+	m.market = market
+}
+
+// CreateNewChar Stores the character, known characters only get their empty fields filled in
+func (m *GameDataManager) CreateNewChar(playerUuid uuid.UUID, playerName string, guildName string, allianceName string) error {
 	if playerName == "" || playerUuid == uuid.Nil {
-		panic("Game information is incorrect")
+		return InvalidCharacter
 	}
 
-	m.CreateNewChar(playerUuid, playerName)
+	value, loaded := m.Characters.LoadOrStore(playerUuid, &Character{
+		ID:       playerUuid,
+		Name:     playerName,
+		Guild:    guildName,
+		Alliance: allianceName,
+	})
+	m.identities.Know(playerUuid, playerName)
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	// Characters show up again on every zone change, only write them when something is new
+	char := value.(*Character)
+	if loaded {
+		if (guildName == "" || guildName == char.Guild) && (allianceName == "" || allianceName == char.Alliance) {
+			return nil
+		}
+
+		if guildName != "" {
+			char.Guild = guildName
+		}
+		if allianceName != "" {
+			char.Alliance = allianceName
+		}
+	}
+
+	m.saveCharacter(char)
+
+	return nil
+}
+
+func (m *GameDataManager) Initialize(playerUuid uuid.UUID, playerName string, guildName string, allianceName string) error {
+	if err := m.CreateNewChar(playerUuid, playerName, guildName, allianceName); err != nil {
+		return err
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
 
 	m.CurrentUser = playerUuid
+
+	return nil
+}
+
+// ChangeCluster Moves the player to the zone, false when the cluster is unknown or the player is already there
+func (m *GameDataManager) ChangeCluster(cluster string) bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if cluster == "" || cluster == m.CurrentCluster {
+		return false
+	}
+
+	m.CurrentCluster = cluster
+
+	return true
+}
+
+func (m *GameDataManager) GetCluster() string {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.CurrentCluster
 }
 
-func (m *GameDataManager) CreatePartyOrUpdate(leader uuid.UUID, members []uuid.UUID) (*Party, bool, []uuid.UUID, []uuid.UUID) {
+// UpdateSelfStats Applies the guild and alliance of the stats event to the current user
+func (m *GameDataManager) UpdateSelfStats(playerName string, guildName string, allianceName string) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	value, ok := m.Characters.Load(m.CurrentUser)
+	if !ok || value.(*Character).Name != playerName {
+		return
+	}
+
+	char := value.(*Character)
+	char.Guild = guildName
+	char.Alliance = allianceName
+
+	m.saveCharacter(char)
+}
+
+func (m *GameDataManager) CreatePartyOrUpdate(leader uuid.UUID, members []uuid.UUID, at time.Time) (*Party, bool, []uuid.UUID, []uuid.UUID) {
 	value, ok := m.Parties.Load(leader)
 	if ok {
 		party := value.(*Party)
-		removedPlayers, addedPlayers := party.SetMembers(members)
+		removedPlayers, addedPlayers := party.SetMembers(members, at)
 		return value.(*Party), false, removedPlayers, addedPlayers
 	}
 
-	value = NewParty(leader, members)
+	value = NewParty(leader, members, at)
 	m.Parties.Store(leader, value)
 
 	return value.(*Party), true, make([]uuid.UUID, 0), members
 }
 
-func (m *GameDataManager) DisbandParty(party *Party) {
+// JoinParty Makes the party the current one, a party the user is already in is updated in place
+func (m *GameDataManager) JoinParty(leader uuid.UUID, members []uuid.UUID, at time.Time) *Party {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.CurrentParty != nil && m.CurrentParty.PartyOwner != leader {
+		// The old party is closed under its own ID before the new one takes over
+		n := len(m.CurrentParty.History)
+		m.CurrentParty.Disband(at)
+		m.saveHistory(m.CurrentParty, n)
+
+		n = len(m.CurrentParty.History)
+		m.Parties.Delete(m.CurrentParty.PartyOwner)
+		m.CurrentParty.Reform(leader, members, at)
+		m.Parties.Store(leader, m.CurrentParty)
+		m.saveHistory(m.CurrentParty, n)
+		return m.CurrentParty
+	}
+
+	n := 0
+	if m.CurrentParty != nil {
+		n = len(m.CurrentParty.History)
+	}
+
+	party, created, _, _ := m.CreatePartyOrUpdate(leader, members, at)
+	if created || party != m.CurrentParty {
+		n = 0
+	}
+
+	m.CurrentParty = party
+	m.saveHistory(party, n)
+
+	return party
+}
+
+// AddPartyMember Adds the player to the current party, false when the user is not in a party
+func (m *GameDataManager) AddPartyMember(playerUuid uuid.UUID, at time.Time) bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.CurrentParty == nil {
+		return false
+	}
+
+	n := len(m.CurrentParty.History)
+	m.CurrentParty.AddPlayer(playerUuid, at)
+	m.saveHistory(m.CurrentParty, n)
+
+	return true
+}
+
+// RemovePartyMember Removes the player from the current party, when it is the user the party is left behind
+func (m *GameDataManager) RemovePartyMember(playerUuid uuid.UUID, at time.Time) bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.CurrentParty == nil {
+		return false
+	}
+
+	n := len(m.CurrentParty.History)
+
+	if playerUuid != m.CurrentUser {
+		m.CurrentParty.RemovePlayer(playerUuid, at)
+		m.saveHistory(m.CurrentParty, n)
+		return true
+	}
+
+	m.CurrentParty.RemoveSelf(playerUuid, at)
+	m.saveHistory(m.CurrentParty, n)
+	m.Parties.Delete(m.CurrentParty.PartyOwner)
+	m.CurrentParty = nil
+
+	return true
+}
+
+// ChangePartyLeader Hands the current party over, Parties is keyed by the leader so the entry moves along
+func (m *GameDataManager) ChangePartyLeader(leader uuid.UUID, at time.Time) bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.CurrentParty == nil {
+		return false
+	}
+
+	n := len(m.CurrentParty.History)
+	m.Parties.Delete(m.CurrentParty.PartyOwner)
+	m.CurrentParty.ChangeLeader(leader, at)
+	m.Parties.Store(m.CurrentParty.PartyOwner, m.CurrentParty)
+	m.saveHistory(m.CurrentParty, n)
+
+	return true
+}
+
+// SetPartyRoleFlag Sets the role flag of a member of the current party, false when there is nothing to update
+func (m *GameDataManager) SetPartyRoleFlag(playerUuid uuid.UUID, flag int) bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.CurrentParty != nil && m.CurrentParty.SetRoleFlag(playerUuid, flag)
+}
+
+// SetSelfLootSetting Sets the loot setting of the user in the current party and returns the user, ok is false when
+// the user is not in a party
+func (m *GameDataManager) SetSelfLootSetting(setting int) (uuid.UUID, bool) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.CurrentUser, m.CurrentParty != nil && m.CurrentParty.SetLootSetting(m.CurrentUser, setting)
+}
+
+// CurrentPartyRoles Returns copies of the role flags and loot settings of the current party
+func (m *GameDataManager) CurrentPartyRoles() (roles map[uuid.UUID]int, lootSettings map[uuid.UUID]int) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	roles, lootSettings = make(map[uuid.UUID]int), make(map[uuid.UUID]int)
+	if m.CurrentParty == nil {
+		return roles, lootSettings
+	}
+
+	for id, flag := range m.CurrentParty.Roles {
+		roles[id] = flag
+	}
+	for id, setting := range m.CurrentParty.LootSettings {
+		lootSettings[id] = setting
+	}
+
+	return roles, lootSettings
+}
+
+// DisbandCurrentParty Disbands the current party, false when the user is not in a party
+func (m *GameDataManager) DisbandCurrentParty(at time.Time) bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.CurrentParty == nil {
+		return false
+	}
+
+	n := len(m.CurrentParty.History)
+	m.DisbandParty(m.CurrentParty, at)
+	m.saveHistory(m.CurrentParty, n)
+	m.CurrentParty = nil
+
+	return true
+}
+
+func (m *GameDataManager) DisbandParty(party *Party, at time.Time) {
+	party.Disband(at)
 	m.Parties.Delete(party.PartyOwner)
 }
 
-func (m *GameDataManager) GetUsername(userId uuid.UUID) string {
+func (m *GameDataManager) IsCurrentUser(userId uuid.UUID) bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.CurrentUser == userId
+}
+
+// CurrentPartyMembers Returns the leader and members of the current party, ok is false when the user is not in a party
+func (m *GameDataManager) CurrentPartyMembers() (leader uuid.UUID, members []uuid.UUID, ok bool) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.CurrentParty == nil {
+		return uuid.Nil, nil, false
+	}
+
+	return m.CurrentParty.PartyOwner, m.CurrentParty.Members.Values(), true
+}
+
+// FindCharacter Looks a character up by name, the game names players in invitations and loot events
+func (m *GameDataManager) FindCharacter(name string) (Character, bool) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	found := m.findCharacter(name)
+	if found == nil {
+		return Character{}, false
+	}
+
+	return *found, true
+}
+
+// GetCharacter Returns a copy of the character, so it can be read without holding the lock
+func (m *GameDataManager) GetCharacter(userId uuid.UUID) (Character, bool) {
 	value, ok := m.Characters.Load(userId)
 	if !ok {
-		return ""
+		return Character{}, false
 	}
 
-	return value.(string)
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return *value.(*Character), true
+}
+
+func (m *GameDataManager) GetUsername(userId uuid.UUID) string {
+	char, _ := m.GetCharacter(userId)
+	return char.Name
 }
 
 func (m *GameDataManager) GetSelfUsername() string {
-	return m.GetUsername(m.CurrentUser)
+	m.mx.Lock()
+	currentUser := m.CurrentUser
+	m.mx.Unlock()
+
+	return m.GetUsername(currentUser)
+}
+
+// Fighters Returns the members of the current party, or the player alone outside a party
+func (m *GameDataManager) Fighters() []uuid.UUID {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.CurrentParty == nil {
+		return []uuid.UUID{m.CurrentUser}
+	}
+
+	return m.CurrentParty.Members.Values()
+}
+
+// UpdateMarketValue Stores the game's estimate of an item, value is in 1/10000 silver
+func (m *GameDataManager) UpdateMarketValue(index int, value int64) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.market == nil || value <= 0 {
+		return
+	}
+
+	m.market.Set(m.item(index), pricing.Price{Value: int64(math.Round(protocol.DecodeFixedPoint(value))), Source: "game"})
+}
+
+// LootValue Returns the estimated value of the loot in silver, 0 when no price source knows the item
+func (m *GameDataManager) LootValue(silver bool, index int, quantity int) int64 {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.lootValue(silver, index, quantity)
+}
+
+// SaveAuditEntry Persists a join of the audit trail
+func (m *GameDataManager) SaveAuditEntry(entry audit.Entry) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.store == nil {
+		return
+	}
+
+	err := m.store.SaveAuditEntry(storage.AuditEntry{
+		Kind:       entry.Kind.String(),
+		PlayerName: entry.PlayerName,
+		Guild:      entry.Guild,
+		Alliance:   entry.Alliance,
+		Warnings:   entry.Warnings,
+		Timestamp:  entry.At,
+	})
+	if err != nil {
+		log.Printf("Failed to save audit entry: %v", err)
+	}
 }
 
-func (m *GameDataManager) Close(todo context.Context) {
+// SaveLootChest Persists the history of a despawned chest
+func (m *GameDataManager) SaveLootChest(chest lootchest.Chest) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
 
+	if m.store == nil {
+		return
+	}
+
+	record := storage.LootChest{
+		ID:        chest.ID,
+		Owner:     chest.Owner,
+		Kind:      chest.Kind.String(),
+		Cluster:   chest.Cluster,
+		Timestamp: chest.Spawned,
+		Despawned: chest.Despawned,
+		Openers:   chest.Openers,
+	}
+
+	for _, stack := range chest.Contents {
+		record.Contents = append(record.Contents, storage.LootItem{ItemIndex: stack.ItemIndex, Quantity: stack.Quantity})
+	}
+
+	for _, take := range chest.Takes {
+		record.Takes = append(record.Takes, storage.LootTake{
+			Looter:    take.Looter,
+			Silver:    take.Silver,
+			ItemIndex: take.ItemIndex,
+			Quantity:  take.Quantity,
+			At:        take.At,
+		})
+	}
+
+	if err := m.store.SaveLootChest(record); err != nil {
+		log.Printf("Failed to save loot chest: %v", err)
+	}
+}
+
+// RecordGrabbedLoot Persists an item or silver being looted and adds it to the loot of the current party
+func (m *GameDataManager) RecordGrabbedLoot(lootedFrom string, lootedBy string, silver bool, index int, quantity int, now time.Time) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.CurrentParty != nil {
+		m.CurrentParty.AddPickup(lootsplit.Pickup{
+			Looter:    lootedBy,
+			From:      lootedFrom,
+			Silver:    silver,
+			ItemIndex: index,
+			Quantity:  quantity,
+			At:        now,
+		})
+	}
+
+	if m.store == nil {
+		return
+	}
+
+	err := m.store.SaveGrabbedLoot(storage.GrabbedLoot{
+		LootedFrom: lootedFrom,
+		LootedBy:   lootedBy,
+		IsSilver:   silver,
+		ItemIndex:  index,
+		ItemName:   m.itemName(silver, index),
+		Value:      m.lootValue(silver, index, quantity),
+		Quantity:   quantity,
+		Cluster:    m.CurrentCluster,
+		Timestamp:  now,
+	})
+	if err != nil {
+		log.Printf("Failed to save grabbed loot: %v", err)
+	}
+}
+
+// SplitCurrentParty Splits the loot of the current party up to end, the leader is the caller unless rules name one
+func (m *GameDataManager) SplitCurrentParty(rules lootsplit.Rules, end time.Time) (*lootsplit.Report, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.CurrentParty == nil {
+		return nil, NotInParty
+	}
+
+	if rules.Caller == uuid.Nil {
+		rules.Caller = m.CurrentParty.PartyOwner
+	}
+
+	if rules.ItemValue == nil {
+		rules.ItemValue = m.itemValue
+	}
+
+	session := m.CurrentParty.Session(end, func(id uuid.UUID) string {
+		value, ok := m.Characters.Load(id)
+		if !ok {
+			return ""
+		}
+
+		return value.(*Character).Name
+	})
+
+	return lootsplit.Split(session, rules)
+}
+
+// Close Closes the store, ctx bounds how long to wait for it to flush
+func (m *GameDataManager) Close(ctx context.Context) error {
+	m.mx.Lock()
+	store := m.store
+	m.store = nil
+	m.mx.Unlock()
+
+	if store == nil {
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- store.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LootName Returns a readable name for grabbed loot
+func (m *GameDataManager) LootName(silver bool, index int) string {
+	if silver {
+		return "silver"
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.items.Name(index)
+}
+
+// item Returns the item of the index, only the index is set for items missing from the dump, mx must be held
+func (m *GameDataManager) item(index int) items.Item {
+	item, ok := m.items.Lookup(index)
+	if !ok {
+		return items.Item{Index: index}
+	}
+
+	return item
+}
+
+// itemValue Returns the price of a single item, mx must be held
+func (m *GameDataManager) itemValue(index int) int64 {
+	if m.prices == nil {
+		return 0
+	}
+
+	price, _ := m.prices.Price(m.item(index))
+	return price.Value
+}
+
+// lootValue LootValue for callers holding mx
+func (m *GameDataManager) lootValue(silver bool, index int, quantity int) int64 {
+	if silver {
+		return int64(protocol.DecodeFixedPoint(int64(quantity)))
+	}
+
+	return m.itemValue(index) * int64(quantity)
+}
+
+// itemName Returns the unique name of the item, empty for silver and unknown items
+func (m *GameDataManager) itemName(silver bool, index int) string {
+	if silver {
+		return ""
+	}
+
+	item, _ := m.items.Lookup(index)
+	return item.UniqueName
+}
+
+// findCharacter Returns the character of the name, nil when it isn't known. mx must be held.
+func (m *GameDataManager) findCharacter(name string) *Character {
+	char, ok := m.identities.ByName(name)
+	if !ok {
+		return nil
+	}
+
+	value, ok := m.Characters.Load(char.ID)
+	if !ok {
+		return nil
+	}
+
+	return value.(*Character)
+}
+
+// saveCharacter Persists the character, mx must be held
+func (m *GameDataManager) saveCharacter(char *Character) {
+	if m.store == nil {
+		return
+	}
+
+	err := m.store.SaveCharacter(storage.Character{
+		ID:       char.ID,
+		Name:     char.Name,
+		Guild:    char.Guild,
+		Alliance: char.Alliance,
+		LastSeen: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to save character: %v", err)
+	}
+}
+
+// saveHistory Persists the History entries of the party from index n on, mx must be held
+func (m *GameDataManager) saveHistory(party *Party, n int) {
+	if m.store == nil {
+		return
+	}
+
+	for _, entry := range party.History[n:] {
+		err := m.store.SavePartyEvent(storage.PartyEvent{
+			Party:     party.ID,
+			Leader:    party.PartyOwner,
+			Action:    entry.Action.String(),
+			User:      entry.User,
+			Cluster:   m.CurrentCluster,
+			Timestamp: entry.Timestamp,
+		})
+		if err != nil {
+			log.Printf("Failed to save party event: %v", err)
+		}
+	}
 }
@@ -0,0 +1,121 @@
+// Package audit Keeps a trail of the players joining the party, and flags the ones which look like someone
+// slipping into the party who shouldn't be there.
+package audit
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:generate stringer -type=Kind
+type Kind int
+
+const (
+	KindJoined Kind = iota
+)
+
+// Entry Player is the joining player. Guild and Alliance are filled in from the characters the logger knows.
+type Entry struct {
+	Kind       Kind      `json:"kind"`
+	At         time.Time `json:"at"`
+	PlayerName string    `json:"playerName"`
+	Guild      string    `json:"guild,omitempty"`
+	Alliance   string    `json:"alliance,omitempty"`
+	Warnings   []string  `json:"warnings,omitempty"`
+}
+
+// Policy What counts as trusted, empty lists trust everyone
+type Policy struct {
+	Guilds    []string
+	Alliances []string
+}
+
+// Log The trail of one session, Record adds the warnings of every entry
+type Log struct {
+	mx      *sync.Mutex
+	policy  Policy
+	entries []Entry
+}
+
+func NewLog(policy Policy) *Log {
+	return &Log{mx: new(sync.Mutex), policy: policy, entries: make([]Entry, 0)}
+}
+
+// ParseList Splits a comma separated flag value, blanks are dropped
+func ParseList(value string) []string {
+	list := make([]string, 0)
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// Record Checks the entry against the policy, adds it and returns it with its warnings.
+// known is false when the logger never saw the character, so guild and alliance are unknown.
+func (l *Log) Record(entry Entry, known bool) Entry {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if entry.At.IsZero() {
+		entry.At = time.Now().UTC()
+	}
+
+	entry.Warnings = l.check(entry, known)
+	l.entries = append(l.entries, entry)
+
+	return entry
+}
+
+// Entries Returns a copy of the trail
+func (l *Log) Entries() []Entry {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return append([]Entry(nil), l.entries...)
+}
+
+func (l *Log) check(entry Entry, known bool) []string {
+	warnings := make([]string, 0)
+
+	switch {
+	case !known && (len(l.policy.Guilds) > 0 || len(l.policy.Alliances) > 0):
+		warnings = append(warnings, "unknown character")
+	case !l.trusts(entry):
+		warnings = append(warnings, "untrusted guild "+quote(entry.Guild)+" and alliance "+quote(entry.Alliance))
+	}
+
+	return warnings
+}
+
+// trusts A player is trusted when their guild or alliance is on the lists, or when there are no lists at all
+func (l *Log) trusts(entry Entry) bool {
+	if len(l.policy.Guilds) == 0 && len(l.policy.Alliances) == 0 {
+		return true
+	}
+
+	return (entry.Guild != "" && contains(l.policy.Guilds, entry.Guild)) ||
+		(entry.Alliance != "" && contains(l.policy.Alliances, entry.Alliance))
+}
+
+func contains(list []string, name string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func quote(name string) string {
+	if name == "" {
+		return "(none)"
+	}
+
+	return name
+}
@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordTrust(t *testing.T) {
+	start := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	log := NewLog(Policy{Guilds: ParseList(" Moodswings, "), Alliances: []string{"BAND"}})
+
+	tests := []struct {
+		name     string
+		entry    Entry
+		known    bool
+		warnings int
+	}{
+		{"trusted guild", Entry{Kind: KindJoined, PlayerName: "Anna", Guild: "moodswings"}, true, 0},
+		{"trusted alliance", Entry{Kind: KindJoined, PlayerName: "Bob", Guild: "Other", Alliance: "BAND"}, true, 0},
+		{"untrusted", Entry{Kind: KindJoined, PlayerName: "Cleo", Guild: "Other"}, true, 1},
+		{"unknown", Entry{Kind: KindJoined, PlayerName: "Dave"}, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.entry.At = start
+			if got := log.Record(tt.entry, tt.known); len(got.Warnings) != tt.warnings {
+				t.Errorf("warnings %v, want %d", got.Warnings, tt.warnings)
+			}
+		})
+	}
+
+	if got := len(log.Entries()); got != len(tests) {
+		t.Errorf("%d entries, want %d", got, len(tests))
+	}
+}
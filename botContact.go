@@ -1,6 +1,12 @@
 package main
 
 import (
+	"M00DSWINGS/audit"
+	"M00DSWINGS/combat"
+	"M00DSWINGS/items"
+	"M00DSWINGS/lootchest"
+	"M00DSWINGS/lootsplit"
+	"M00DSWINGS/meter"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -21,6 +27,8 @@ type WebSocketClient struct {
 	charcterName string
 	allianceName string
 	guildName    string
+	onConnect    func()
+	items        *items.Database
 }
 
 func NewWebSocketClient(url string) *WebSocketClient {
@@ -60,6 +68,14 @@ func (c *WebSocketClient) Connect(ctx context.Context) error {
 		go c.readLoop(ctx)
 		go c.writeLoop(ctx)
 
+		c.sendMx.Lock()
+		onConnect := c.onConnect
+		c.sendMx.Unlock()
+
+		if onConnect != nil {
+			onConnect()
+		}
+
 		return nil
 	}
 }
@@ -120,6 +136,36 @@ func (c *WebSocketClient) reconnect(ctx context.Context) {
 	go c.Connect(ctx)
 }
 
+// RegisterConnect Sets a callback which runs after every (re)connect, once initialize has been queued
+func (c *WebSocketClient) RegisterConnect(f func()) {
+	c.sendMx.Lock()
+	defer c.sendMx.Unlock()
+
+	c.onConnect = f
+}
+
+// RegisterItems Adds the resolved item to every message carrying an item index
+func (c *WebSocketClient) RegisterItems(db *items.Database) {
+	c.sendMx.Lock()
+	defer c.sendMx.Unlock()
+
+	c.items = db
+}
+
+// item Returns the item for the message, nil when the index is unknown
+func (c *WebSocketClient) item(index int) interface{} {
+	c.sendMx.Lock()
+	db := c.items
+	c.sendMx.Unlock()
+
+	item, ok := db.Lookup(index)
+	if !ok {
+		return nil
+	}
+
+	return item
+}
+
 func (c *WebSocketClient) Send(ctx context.Context, message map[string]interface{}) error {
 	msg, err := json.Marshal(message)
 	if err != nil {
@@ -269,13 +315,48 @@ func (c *WebSocketClient) UpdatePartyLeader(leader uuid.UUID) error {
 	return nil
 }
 
-func (c *WebSocketClient) AttachItemContainer(id int, uuid uuid.UUID, items []int, slots int) error {
+func (c *WebSocketClient) UpdatePartyRoleFlag(uid uuid.UUID, flag int) error {
 	msg := map[string]interface{}{
-		"action": "attach_item_container",
-		"id":     id,
-		"uuid":   uuid,
-		"items":  items,
-		"slots":  slots,
+		"action": "party_role_flag",
+		"id":     uid,
+		"flag":   flag,
+	}
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("Failed to send party_role_flag message: %v\n", err)
+	}
+
+	return nil
+}
+
+func (c *WebSocketClient) UpdatePartyLootSetting(uid uuid.UUID, setting int) error {
+	msg := map[string]interface{}{
+		"action":  "party_loot_setting",
+		"id":      uid,
+		"setting": setting,
+	}
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("Failed to send party_loot_setting message: %v\n", err)
+	}
+
+	return nil
+}
+
+// AttachItemContainer indices holds the item index of every object in objects, 0 for objects not seen yet
+func (c *WebSocketClient) AttachItemContainer(id int, uuid uuid.UUID, objects []int, slots int, indices []int) error {
+	resolved := make([]interface{}, len(indices))
+	for i, index := range indices {
+		resolved[i] = c.item(index)
+	}
+
+	msg := map[string]interface{}{
+		"action":   "attach_item_container",
+		"id":       id,
+		"uuid":     uuid,
+		"items":    objects,
+		"slots":    slots,
+		"resolved": resolved,
 	}
 
 	if err := c.Send(context.Background(), msg); err != nil {
@@ -356,14 +437,17 @@ func (c *WebSocketClient) UpdateLootChest(id int) error {
 	return nil
 }
 
-func (c *WebSocketClient) OtherGrabLoot(lootedFrom string, lootedBy string, silver bool, index int, quantity int) error {
+// OtherGrabLoot value is the estimated value of the whole pickup in silver
+func (c *WebSocketClient) OtherGrabLoot(lootedFrom string, lootedBy string, silver bool, index int, quantity int, value int64) error {
 	msg := map[string]interface{}{
 		"action":     "other_grab_loot",
 		"lootedFrom": lootedFrom,
 		"lootedBy":   lootedBy,
 		"silver":     silver,
 		"index":      index,
+		"item":       c.item(index),
 		"quantity":   quantity,
+		"value":      value,
 	}
 
 	if err := c.Send(context.Background(), msg); err != nil {
@@ -386,12 +470,15 @@ func (c *WebSocketClient) DetachItemContainer(containerUUID uuid.UUID) error {
 	return nil
 }
 
-func (c *WebSocketClient) NewSimpleItem(id int, index int, quantity int) error {
+// NewSimpleItem value is the estimated value of the whole stack in silver
+func (c *WebSocketClient) NewSimpleItem(id int, index int, quantity int, value int64) error {
 	msg := map[string]interface{}{
 		"action":   "new_simple_item",
 		"id":       id,
 		"index":    index,
+		"item":     c.item(index),
 		"quantity": quantity,
+		"value":    value,
 	}
 
 	if err := c.Send(context.Background(), msg); err != nil {
@@ -414,3 +501,180 @@ func (c *WebSocketClient) PartyReadCheck(members []uuid.UUID, status []int) erro
 
 	return nil
 }
+
+func (c *WebSocketClient) LootSplit(report *lootsplit.Report) error {
+	entries := make([]map[string]interface{}, 0, len(report.Entries))
+	for _, entry := range report.Entries {
+		entries = append(entries, map[string]interface{}{
+			"id":      entry.Member,
+			"name":    entry.Name,
+			"seconds": int64(entry.Weight / time.Second),
+			"share":   entry.Share,
+			"holding": entry.Holding,
+			"balance": entry.Balance,
+		})
+	}
+
+	msg := map[string]interface{}{
+		"action":    "loot_split",
+		"mode":      report.Mode.String(),
+		"total":     report.Total,
+		"callerCut": report.CallerCut,
+		"ignored":   report.Ignored,
+		"entries":   entries,
+	}
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("Failed to send loot_split message: %v\n", err)
+	}
+
+	return nil
+}
+
+func (c *WebSocketClient) LootChestDespawned(chest lootchest.Chest) error {
+	contents := make([]map[string]interface{}, 0, len(chest.Contents))
+	for _, stack := range chest.Contents {
+		contents = append(contents, map[string]interface{}{
+			"index":    stack.ItemIndex,
+			"item":     c.item(stack.ItemIndex),
+			"quantity": stack.Quantity,
+		})
+	}
+
+	takes := make([]map[string]interface{}, 0, len(chest.Takes))
+	for _, take := range chest.Takes {
+		takes = append(takes, map[string]interface{}{
+			"looter":   take.Looter,
+			"silver":   take.Silver,
+			"index":    take.ItemIndex,
+			"item":     c.item(take.ItemIndex),
+			"quantity": take.Quantity,
+			"at":       take.At,
+		})
+	}
+
+	msg := map[string]interface{}{
+		"action":    "loot_chest_despawned",
+		"id":        chest.ID,
+		"owner":     chest.Owner,
+		"kind":      chest.Kind.String(),
+		"cluster":   chest.Cluster,
+		"spawned":   chest.Spawned,
+		"despawned": chest.Despawned,
+		"openers":   chest.Openers,
+		"contents":  contents,
+		"takes":     takes,
+	}
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("Failed to send loot_chest_despawned message: %v\n", err)
+	}
+
+	return nil
+}
+
+func (c *WebSocketClient) PartyAudit(entry audit.Entry) error {
+	msg := map[string]interface{}{
+		"action":   "party_audit",
+		"kind":     entry.Kind.String(),
+		"at":       entry.At,
+		"name":     entry.PlayerName,
+		"guild":    entry.Guild,
+		"alliance": entry.Alliance,
+		"warnings": entry.Warnings,
+	}
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("Failed to send party_audit message: %v\n", err)
+	}
+
+	return nil
+}
+
+func (c *WebSocketClient) ChangeCluster(cluster string) error {
+	msg := map[string]interface{}{
+		"action":  "change_cluster",
+		"cluster": cluster,
+	}
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("Failed to send change_cluster message: %v\n", err)
+	}
+
+	return nil
+}
+
+// SessionMeter Sends the live totals of the session, or the session summary when final
+func (c *WebSocketClient) SessionMeter(summary meter.Summary, final bool) error {
+	action := "session_meter"
+	if final {
+		action = "session_summary"
+	}
+
+	clusters := make([]map[string]interface{}, 0, len(summary.Clusters))
+	for _, cluster := range summary.Clusters {
+		entry := totals(cluster.Totals)
+		entry["cluster"] = cluster.Cluster
+
+		clusters = append(clusters, entry)
+	}
+
+	msg := map[string]interface{}{
+		"action":   action,
+		"start":    summary.Start,
+		"end":      summary.End,
+		"session":  totals(summary.Session),
+		"clusters": clusters,
+	}
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("Failed to send %s message: %v\n", action, err)
+	}
+
+	return nil
+}
+
+func totals(t meter.Totals) map[string]interface{} {
+	return map[string]interface{}{
+		"fame":          t.Fame,
+		"silver":        t.Silver,
+		"balance":       t.Balance,
+		"seconds":       int64(t.Duration / time.Second),
+		"famePerHour":   t.FamePerHour,
+		"silverPerHour": t.SilverPerHour,
+	}
+}
+
+// CombatFight Sends the running fight, or the fight report once it ended when final
+func (c *WebSocketClient) CombatFight(fight combat.Fight, final bool) error {
+	action := "combat_live"
+	if final {
+		action = "combat_fight"
+	}
+
+	members := make([]map[string]interface{}, 0, len(fight.Members))
+	for _, stats := range fight.Members {
+		members = append(members, map[string]interface{}{
+			"id":      stats.ID,
+			"name":    stats.Name,
+			"damage":  stats.Damage,
+			"healing": stats.Healing,
+			"taken":   stats.Taken,
+			"hits":    stats.Hits,
+		})
+	}
+
+	msg := map[string]interface{}{
+		"action":  action,
+		"start":   fight.Start,
+		"end":     fight.End,
+		"cluster": fight.Cluster,
+		"members": members,
+	}
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("Failed to send %s message: %v\n", action, err)
+	}
+
+	return nil
+}
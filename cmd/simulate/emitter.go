@@ -0,0 +1,140 @@
+package main
+
+import (
+	"M00DSWINGS/protocol/photon"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"net"
+	"os"
+	"time"
+)
+
+const serverPort = 5056
+
+// datagram Wraps the command into a datagram of its own, like the game server sends fragments. The timestamp counts
+// milliseconds since the first command.
+func datagram(start time.Time, at time.Time, command photon.Command) ([]byte, error) {
+	return photon.Layer{
+		PeerID:    1,
+		Timestamp: uint32(at.Sub(start) / time.Millisecond),
+		Commands:  []photon.Command{command},
+	}.Encode()
+}
+
+// PcapEmitter Writes every command as an Ethernet/IPv4/UDP frame coming from the game server
+type PcapEmitter struct {
+	file   *os.File
+	writer *pcapgo.Writer
+	start  time.Time
+}
+
+func NewPcapEmitter(path string) (*PcapEmitter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := pcapgo.NewWriter(file)
+	if err := writer.WriteFileHeader(65535, layers.LinkTypeEthernet); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &PcapEmitter{file: file, writer: writer}, nil
+}
+
+func (e *PcapEmitter) Emit(at time.Time, command photon.Command) error {
+	if e.start.IsZero() {
+		e.start = at
+	}
+
+	payload, err := datagram(e.start, at, command)
+	if err != nil {
+		return err
+	}
+
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x02, 0, 0, 0, 0, 1},
+		DstMAC:       net.HardwareAddr{0x02, 0, 0, 0, 0, 2},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.IP{10, 0, 0, 1},
+		DstIP:    net.IP{192, 168, 1, 2},
+	}
+	udp := &layers.UDP{
+		SrcPort: serverPort,
+		DstPort: 50000,
+	}
+
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		return err
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+
+	if err := gopacket.SerializeLayers(buf, opts, eth, ip, udp, gopacket.Payload(payload)); err != nil {
+		return err
+	}
+
+	frame := buf.Bytes()
+
+	return e.writer.WritePacket(gopacket.CaptureInfo{
+		Timestamp:     at,
+		CaptureLength: len(frame),
+		Length:        len(frame),
+	}, frame)
+}
+
+func (e *PcapEmitter) Close() error {
+	return e.file.Close()
+}
+
+// UDPEmitter Sends every command from the game server port, keeping the delays of the scenario
+type UDPEmitter struct {
+	conn    *net.UDPConn
+	started time.Time
+	first   time.Time
+}
+
+func NewUDPEmitter(addr string) (*UDPEmitter, error) {
+	remote, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp", &net.UDPAddr{Port: serverPort}, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UDPEmitter{conn: conn}, nil
+}
+
+func (e *UDPEmitter) Emit(at time.Time, command photon.Command) error {
+	if e.started.IsZero() {
+		e.started = time.Now()
+		e.first = at
+	}
+
+	if wait := time.Until(e.started.Add(at.Sub(e.first))); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	payload, err := datagram(e.first, at, command)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.conn.Write(payload)
+	return err
+}
+
+func (e *UDPEmitter) Close() error {
+	return e.conn.Close()
+}
@@ -0,0 +1,64 @@
+// Command simulate Plays a scripted game session as Photon traffic, either into a pcap file which
+// the logger can replay with -read, or as live UDP datagrams from port 5056 which a logger
+// sniffing the loopback interface picks up. The logger plays scenarios without either with -scenario.
+package main
+
+import (
+	"M00DSWINGS/protocol/photon"
+	"M00DSWINGS/scenario"
+	"flag"
+	"log"
+)
+
+var (
+	scenarioPath string
+	outputPath   string
+	sendAddr     string
+	fragmentSize int
+)
+
+// parseFlags Reads the command line, the test binary has flags of its own so this isn't done in init
+func parseFlags() {
+	flag.StringVar(&scenarioPath, "scenario", "", "Scenario file (YAML or JSON)")
+	flag.StringVar(&outputPath, "out", "", "Write the session into this pcap file")
+	flag.StringVar(&sendAddr, "send", "", "Send the session as UDP datagrams to this address, e.g. 127.0.0.1:50000")
+	flag.IntVar(&fragmentSize, "fragment-size", photon.DefaultFragmentSize, "Messages larger than this are fragmented")
+
+	flag.Parse()
+
+	if scenarioPath == "" {
+		log.Fatal("scenario is required")
+	}
+
+	if (outputPath == "") == (sendAddr == "") {
+		log.Fatal("either -out or -send is required")
+	}
+}
+
+func main() {
+	parseFlags()
+
+	s, err := scenario.Load(scenarioPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var emitter scenario.Emitter
+	if outputPath != "" {
+		emitter, err = NewPcapEmitter(outputPath)
+	} else {
+		emitter, err = NewUDPEmitter(sendAddr)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer emitter.Close()
+
+	err = s.Play(emitter, fragmentSize, func(i int, step scenario.Step) {
+		log.Printf("Step %d: %s", i, step.Packet)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"M00DSWINGS/protocol/packets"
+	"M00DSWINGS/protocol/photon"
+	"M00DSWINGS/scenario"
+	"github.com/google/gopacket"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	at       []time.Time
+	commands []photon.Command
+}
+
+func (r *recorder) Emit(at time.Time, command photon.Command) error {
+	r.at = append(r.at, at)
+	r.commands = append(r.commands, command)
+	return nil
+}
+
+func (r *recorder) Close() error {
+	return nil
+}
+
+func TestPartyLootScenario(t *testing.T) {
+	s, err := scenario.Load("scenarios/party_loot.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A small fragment size makes the larger steps go through the fragment buffer as well
+	r := new(recorder)
+	if err := s.Play(r, 64, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	fragments := photon.NewFragmentBuffer()
+	messages := make([]photon.ReliableMessageParamaters, 0, len(s.Steps))
+	fragmented := false
+
+	for i, command := range r.commands {
+		// Every command has to survive the datagram the pcap and UDP emitters wrap it in
+		payload, err := datagram(s.Start, r.at[i], command)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		layer, ok := gopacket.NewPacket(payload, photon.LayerType, gopacket.Default).Layer(photon.LayerType).(photon.Layer)
+		if !ok || len(layer.Commands) != 1 {
+			t.Fatalf("datagram %d did not decode", i)
+		}
+
+		command := layer.Commands[0]
+
+		if command.Type == photon.SendReliableFragmentType {
+			fragmented = true
+
+			fragment, err := command.ReliableFragment()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assembled := fragments.Offer(fragment)
+			if assembled == nil {
+				continue
+			}
+
+			command = *assembled
+		}
+
+		_, params, err := photon.Protocol16{}.Deserialize(command)
+		if err != nil {
+			t.Fatalf("command %d: %v", i, err)
+		}
+
+		messages = append(messages, params)
+	}
+
+	if !fragmented {
+		t.Error("no step was fragmented")
+	}
+
+	if len(messages) != len(s.Steps) {
+		t.Fatalf("%d messages for %d steps", len(messages), len(s.Steps))
+	}
+
+	if want := s.Start.Add(72 * time.Second); !r.at[len(r.at)-1].Equal(want) {
+		t.Errorf("last step at %v, want %v", r.at[len(r.at)-1], want)
+	}
+
+	for i, step := range s.Steps {
+		want, err := step.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := reflect.New(reflect.TypeOf(want).Elem()).Interface()
+		if err := packets.Decode(messages[i], got); err != nil {
+			t.Errorf("step %d: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(normalize(got), normalize(want)) {
+			t.Errorf("step %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+// normalize Replaces empty slices with nil, the game sends empty arrays for fields the scenario leaves out
+func normalize(packet interface{}) interface{} {
+	val := reflect.ValueOf(packet).Elem()
+	copied := reflect.New(val.Type()).Elem()
+	copied.Set(val)
+
+	for i := 0; i < copied.NumField(); i++ {
+		field := copied.Field(i)
+		if field.Kind() == reflect.Slice && field.Len() == 0 && field.CanSet() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+
+	return copied.Interface()
+}
@@ -0,0 +1,206 @@
+// Package combat Adds up the damage, healing and damage taken of party members per fight. A fight starts with the
+// first hit involving the party and ends once the party saw no hits for the idle time.
+package combat
+
+import (
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Combatant The character behind an object ID
+type Combatant struct {
+	ID   uuid.UUID
+	Name string
+}
+
+// Hit Delta is the health change of Target caused by Causer, negative for damage
+type Hit struct {
+	At      time.Time
+	Causer  int
+	Target  int
+	Delta   float64
+	Cluster string
+}
+
+// Stats Hits counts the damaging hits the member dealt
+type Stats struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	Damage  float64   `json:"damage"`
+	Healing float64   `json:"healing"`
+	Taken   float64   `json:"taken"`
+	Hits    int       `json:"hits"`
+}
+
+// Fight Members are ordered by damage done
+type Fight struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Cluster string    `json:"cluster,omitempty"`
+	Members []Stats   `json:"members"`
+}
+
+type fight struct {
+	start   time.Time
+	last    time.Time
+	cluster string
+	members map[uuid.UUID]*Stats
+}
+
+// Resolver Returns the character behind the object ID, ok is false for objects which aren't characters
+type Resolver func(object int) (Combatant, bool)
+
+// Meter Hits of objects the resolver doesn't know are ignored. Finished fights are handed to the caller of the
+// method that ended them, the meter only keeps the running one.
+type Meter struct {
+	mx        *sync.Mutex
+	idle      time.Duration
+	liveEvery time.Duration
+	resolve   Resolver
+	current   *fight
+	lastLive  time.Time
+}
+
+// New idle is how long the party has to be out of combat for a fight to end, live how often Live returns the
+// running fight
+func New(idle time.Duration, live time.Duration, resolve Resolver) *Meter {
+	return &Meter{
+		mx:        new(sync.Mutex),
+		idle:      idle,
+		liveEvery: live,
+		resolve:   resolve,
+	}
+}
+
+// Record Adds the hit to the running fight if it involves the party. When the hit starts a new fight the one
+// before it is returned with ok set.
+func (m *Meter) Record(hit Hit, party []uuid.UUID) (finished Fight, ok bool) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	causer, causerOk := m.member(hit.Causer, party)
+	target, targetOk := m.member(hit.Target, party)
+	if !causerOk && !targetOk {
+		return Fight{}, false
+	}
+
+	if m.current != nil && hit.At.Sub(m.current.last) > m.idle {
+		finished, ok = m.finish()
+	}
+
+	if m.current == nil {
+		m.current = &fight{start: hit.At, cluster: hit.Cluster, members: make(map[uuid.UUID]*Stats)}
+	}
+
+	m.current.last = hit.At
+
+	switch {
+	case hit.Delta < 0:
+		if causerOk {
+			m.current.stats(causer).Damage -= hit.Delta
+			m.current.stats(causer).Hits++
+		}
+		if targetOk {
+			m.current.stats(target).Taken -= hit.Delta
+		}
+	case hit.Delta > 0:
+		if causerOk {
+			m.current.stats(causer).Healing += hit.Delta
+		}
+	}
+
+	return finished, ok
+}
+
+// Live Returns the running fight, at most once per live interval
+func (m *Meter) Live(at time.Time) (Fight, bool) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.current == nil || at.Sub(m.lastLive) < m.liveEvery {
+		return Fight{}, false
+	}
+
+	m.lastLive = at
+
+	return m.current.report(), true
+}
+
+// Expire Ends the running fight once the party saw no hits for the idle time before at, ok is false while it
+// still runs
+func (m *Meter) Expire(at time.Time) (Fight, bool) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.current == nil || at.Sub(m.current.last) <= m.idle {
+		return Fight{}, false
+	}
+
+	return m.finish()
+}
+
+// Close Ends the running fight, ok is false when there was none
+func (m *Meter) Close() (Fight, bool) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.finish()
+}
+
+// member Returns the combatant of the object if it is in the party, mx must be held
+func (m *Meter) member(object int, party []uuid.UUID) (Combatant, bool) {
+	combatant, ok := m.resolve(object)
+	if !ok {
+		return Combatant{}, false
+	}
+
+	for _, id := range party {
+		if id == combatant.ID {
+			return combatant, true
+		}
+	}
+
+	return Combatant{}, false
+}
+
+// finish Ends the running fight, mx must be held
+func (m *Meter) finish() (Fight, bool) {
+	if m.current == nil {
+		return Fight{}, false
+	}
+
+	finished := m.current.report()
+	m.current = nil
+
+	return finished, true
+}
+
+func (f *fight) stats(combatant Combatant) *Stats {
+	stats, ok := f.members[combatant.ID]
+	if !ok {
+		stats = &Stats{ID: combatant.ID, Name: combatant.Name}
+		f.members[combatant.ID] = stats
+	}
+
+	return stats
+}
+
+func (f *fight) report() Fight {
+	report := Fight{Start: f.start, End: f.last, Cluster: f.cluster, Members: make([]Stats, 0, len(f.members))}
+
+	for _, stats := range f.members {
+		report.Members = append(report.Members, *stats)
+	}
+
+	sort.Slice(report.Members, func(i, j int) bool {
+		if report.Members[i].Damage == report.Members[j].Damage {
+			return report.Members[i].Name < report.Members[j].Name
+		}
+
+		return report.Members[i].Damage > report.Members[j].Damage
+	})
+
+	return report
+}
@@ -0,0 +1,88 @@
+package combat
+
+import (
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMeterFights(t *testing.T) {
+	start := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	anna, bob, stranger := uuid.New(), uuid.New(), uuid.New()
+	party := []uuid.UUID{anna, bob}
+
+	objects := map[int]Combatant{
+		1: {ID: anna, Name: "Anna"},
+		2: {ID: bob, Name: "Bob"},
+		3: {ID: stranger, Name: "Dave"},
+	}
+
+	m := New(30*time.Second, time.Second, func(object int) (Combatant, bool) {
+		combatant, ok := objects[object]
+		return combatant, ok
+	})
+
+	// Hits between objects outside the party don't start a fight
+	if _, ok := m.Record(Hit{At: start, Causer: 3, Target: 99, Delta: -50}, party); ok {
+		t.Error("fight finished without one running")
+	}
+	if _, ok := m.Live(start); ok {
+		t.Error("fight started without the party")
+	}
+
+	m.Record(Hit{At: start, Causer: 1, Target: 99, Delta: -100, Cluster: "4000"}, party)
+	m.Record(Hit{At: start.Add(time.Second), Causer: 99, Target: 1, Delta: -40}, party)
+	m.Record(Hit{At: start.Add(2 * time.Second), Causer: 2, Target: 1, Delta: 30}, party)
+	m.Record(Hit{At: start.Add(3 * time.Second), Causer: 2, Target: 99, Delta: -20}, party)
+
+	live, ok := m.Live(start.Add(3 * time.Second))
+	if !ok || len(live.Members) != 2 {
+		t.Fatalf("live fight %+v", live)
+	}
+	if _, ok := m.Live(start.Add(3*time.Second + 500*time.Millisecond)); ok {
+		t.Error("live fight was returned within the interval")
+	}
+
+	// Out of combat for longer than the idle time, the next hit starts a new fight
+	delete(objects, 2)
+	fight, ok := m.Record(Hit{At: start.Add(time.Minute), Causer: 99, Target: 1, Delta: -10}, party)
+	if !ok {
+		t.Fatal("first fight didn't finish")
+	}
+
+	want := []Stats{
+		{ID: anna, Name: "Anna", Damage: 100, Taken: 40, Hits: 1},
+		{ID: bob, Name: "Bob", Damage: 20, Healing: 30, Hits: 1},
+	}
+	if fight.Cluster != "4000" || !fight.End.Equal(start.Add(3*time.Second)) || len(fight.Members) != 2 || fight.Members[0] != want[0] || fight.Members[1] != want[1] {
+		t.Errorf("fight %+v", fight)
+	}
+
+	if _, ok := m.Record(Hit{At: start.Add(time.Minute), Causer: 2, Target: 99, Delta: -10}, party); ok {
+		t.Error("forgotten object was attributed")
+	}
+
+	if _, ok := m.Expire(start.Add(time.Minute + 30*time.Second)); ok {
+		t.Error("fight expired within the idle time")
+	}
+
+	last, ok := m.Expire(start.Add(2 * time.Minute))
+	if !ok || len(last.Members) != 1 || last.Members[0].Taken != 10 {
+		t.Errorf("last fight %+v", last)
+	}
+
+	if _, ok := m.Close(); ok {
+		t.Error("expired fight was closed again")
+	}
+
+	dir := t.TempDir()
+	if err := Save(dir, last); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "fight-20240601-200100.000.json")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,36 @@
+package combat
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func WriteJSON(w io.Writer, fight Fight) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(fight)
+}
+
+// Save Writes the fight report to dir, named after the time the fight started
+func Save(dir string, fight Fight) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	name := filepath.Join(dir, "fight-"+fight.Start.UTC().Format("20060102-150405.000")+".json")
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteJSON(file, fight); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,136 @@
+// Package identity Ties the three ways the game names a player together: the object ID in the cluster, the
+// character UUID and the character name.
+package identity
+
+import (
+	"github.com/google/uuid"
+	"sync"
+)
+
+// Identity Object is 0 when the character has no object in the current cluster
+type Identity struct {
+	Object int
+	ID     uuid.UUID
+	Name   string
+}
+
+// Registry Names and UUIDs are kept for the whole session, object IDs only while the character is in the cluster
+type Registry struct {
+	mx       *sync.Mutex
+	names    map[uuid.UUID]string
+	ids      map[string]uuid.UUID
+	objects  map[int]uuid.UUID
+	objectOf map[uuid.UUID]int
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		mx:       new(sync.Mutex),
+		names:    make(map[uuid.UUID]string),
+		ids:      make(map[string]uuid.UUID),
+		objects:  make(map[int]uuid.UUID),
+		objectOf: make(map[uuid.UUID]int),
+	}
+}
+
+// Know Ties the name to the UUID, for characters seen without an object
+func (r *Registry) Know(id uuid.UUID, name string) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.know(id, name)
+}
+
+// Add Ties the object to the character, replacing the object the character had before
+func (r *Registry) Add(object int, id uuid.UUID, name string) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.know(id, name)
+
+	if previous, ok := r.objectOf[id]; ok {
+		delete(r.objects, previous)
+	}
+	if previous, ok := r.objects[object]; ok {
+		delete(r.objectOf, previous)
+	}
+
+	r.objects[object] = id
+	r.objectOf[id] = object
+}
+
+// Leave Drops the object once it left the cluster and returns who it was, ok is false for objects which aren't
+// characters
+func (r *Registry) Leave(object int) (Identity, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	id, ok := r.objects[object]
+	if !ok {
+		return Identity{}, false
+	}
+
+	delete(r.objects, object)
+	delete(r.objectOf, id)
+
+	return Identity{Object: object, ID: id, Name: r.names[id]}, true
+}
+
+// Clear Drops every object, object IDs are only valid within a cluster
+func (r *Registry) Clear() {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.objects = make(map[int]uuid.UUID)
+	r.objectOf = make(map[uuid.UUID]int)
+}
+
+func (r *Registry) ByObject(object int) (Identity, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	id, ok := r.objects[object]
+	if !ok {
+		return Identity{}, false
+	}
+
+	return Identity{Object: object, ID: id, Name: r.names[id]}, true
+}
+
+func (r *Registry) ByID(id uuid.UUID) (Identity, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	name, ok := r.names[id]
+	if !ok {
+		return Identity{}, false
+	}
+
+	return Identity{Object: r.objectOf[id], ID: id, Name: name}, true
+}
+
+func (r *Registry) ByName(name string) (Identity, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	id, ok := r.ids[name]
+	if !ok {
+		return Identity{}, false
+	}
+
+	return Identity{Object: r.objectOf[id], ID: id, Name: name}, true
+}
+
+// know Renames are followed, the old name no longer resolves. mx must be held.
+func (r *Registry) know(id uuid.UUID, name string) {
+	if name == "" {
+		return
+	}
+
+	if previous, ok := r.names[id]; ok && previous != name && r.ids[previous] == id {
+		delete(r.ids, previous)
+	}
+
+	r.names[id] = name
+	r.ids[name] = id
+}
@@ -0,0 +1,54 @@
+package identity
+
+import (
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	anna, bob := uuid.New(), uuid.New()
+	r := NewRegistry()
+
+	r.Add(10, anna, "Anna")
+	r.Know(bob, "Bob")
+
+	if got, ok := r.ByName("Anna"); !ok || got != (Identity{Object: 10, ID: anna, Name: "Anna"}) {
+		t.Errorf("by name %+v, %v", got, ok)
+	}
+	if got, ok := r.ByID(bob); !ok || got != (Identity{ID: bob, Name: "Bob"}) {
+		t.Errorf("character without an object %+v, %v", got, ok)
+	}
+
+	// A new object of the same character replaces the old one, and a reused object ID changes hands
+	r.Add(11, anna, "Anna")
+	r.Add(10, bob, "Bob")
+
+	if got, ok := r.ByObject(10); !ok || got.ID != bob {
+		t.Errorf("object 10 is %+v, %v", got, ok)
+	}
+	if got, _ := r.ByID(anna); got.Object != 11 {
+		t.Errorf("anna has object %d, want 11", got.Object)
+	}
+
+	if got, ok := r.Leave(11); !ok || got.Name != "Anna" {
+		t.Errorf("left %+v, %v", got, ok)
+	}
+	if _, ok := r.Leave(11); ok {
+		t.Error("object left twice")
+	}
+
+	// The character stays known after leaving the cluster
+	if got, ok := r.ByName("Anna"); !ok || got.Object != 0 {
+		t.Errorf("after leaving %+v, %v", got, ok)
+	}
+
+	r.Clear()
+	if _, ok := r.ByObject(10); ok {
+		t.Error("objects survived the cluster change")
+	}
+
+	r.Know(bob, "Bobby")
+	if _, ok := r.ByName("Bob"); ok {
+		t.Error("old name still resolves")
+	}
+}
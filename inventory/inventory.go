@@ -0,0 +1,242 @@
+// Package inventory A client side model of the item containers the player can see, bags, banks and loot chests
+// alike, built from the inventory events of the game.
+package inventory
+
+import (
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+)
+
+// Stack An item object in the world, Quantity is the stack size
+type Stack struct {
+	Object    int `json:"object"`
+	ItemIndex int `json:"itemIndex"`
+	Quantity  int `json:"quantity"`
+}
+
+// Container Slots holds the object ID in every slot, 0 for empty ones. A detached container keeps its last
+// known contents.
+type Container struct {
+	ID       uuid.UUID
+	Owner    int
+	Slots    []int
+	Attached bool
+
+	// opened The contents when the container was attached, Detach diffs against it
+	opened Snapshot
+}
+
+// Snapshot The quantity of every item index in a container
+type Snapshot map[int]int
+
+// Change The quantity of an item before and after, Delta is After minus Before
+type Change struct {
+	ItemIndex int `json:"itemIndex"`
+	Before    int `json:"before"`
+	After     int `json:"after"`
+	Delta     int `json:"delta"`
+}
+
+type Inventory struct {
+	mx         *sync.RWMutex
+	containers map[uuid.UUID]*Container
+	objects    map[int]Stack
+}
+
+func New() *Inventory {
+	return &Inventory{
+		mx:         new(sync.RWMutex),
+		containers: make(map[uuid.UUID]*Container),
+		objects:    make(map[int]Stack),
+	}
+}
+
+// AddObject Records an item object, containers only refer to their items by object ID
+func (i *Inventory) AddObject(object int, itemIndex int, quantity int) {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+
+	i.objects[object] = Stack{Object: object, ItemIndex: itemIndex, Quantity: quantity}
+}
+
+// Attach Opens a container with the objects of every slot, size is its slot count
+func (i *Inventory) Attach(owner int, id uuid.UUID, objects []int, size int) {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+
+	if size < len(objects) {
+		size = len(objects)
+	}
+
+	slots := make([]int, size)
+	copy(slots, objects)
+
+	for _, object := range slots {
+		if object != 0 {
+			i.remove(object)
+		}
+	}
+
+	c := &Container{ID: id, Owner: owner, Slots: slots, Attached: true}
+	c.opened = i.snapshot(c)
+	i.containers[id] = c
+}
+
+// Detach Closes the container and returns what changed while it was open
+func (i *Inventory) Detach(id uuid.UUID) []Change {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+
+	c, ok := i.containers[id]
+	if !ok || !c.Attached {
+		return nil
+	}
+
+	c.Attached = false
+
+	return Diff(c.opened, i.snapshot(c))
+}
+
+// Put Places the object into a slot, taking it out of wherever it was before
+func (i *Inventory) Put(object int, id uuid.UUID, slot int) {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+
+	c, ok := i.containers[id]
+	if !ok || slot < 0 {
+		return
+	}
+
+	i.remove(object)
+	c.grow(slot)
+	c.Slots[slot] = object
+}
+
+// Move Swaps two slots, moving onto an empty slot leaves the source empty
+func (i *Inventory) Move(fromSlot int, from uuid.UUID, toSlot int, to uuid.UUID) {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+
+	src, ok := i.containers[from]
+	if !ok || fromSlot < 0 || fromSlot >= len(src.Slots) || toSlot < 0 {
+		return
+	}
+
+	dst, ok := i.containers[to]
+	if !ok {
+		return
+	}
+
+	dst.grow(toSlot)
+	src.Slots[fromSlot], dst.Slots[toSlot] = dst.Slots[toSlot], src.Slots[fromSlot]
+}
+
+// Contents Returns the stacks of the container in slot order, objects which were never seen have item index 0
+func (i *Inventory) Contents(id uuid.UUID) ([]Stack, bool) {
+	i.mx.RLock()
+	defer i.mx.RUnlock()
+
+	c, ok := i.containers[id]
+	if !ok {
+		return nil, false
+	}
+
+	stacks := make([]Stack, 0, len(c.Slots))
+	for _, object := range c.Slots {
+		if object != 0 {
+			stacks = append(stacks, i.stack(object))
+		}
+	}
+
+	return stacks, true
+}
+
+// Snapshot Returns the current quantities of the container, to be diffed against a later one
+func (i *Inventory) Snapshot(id uuid.UUID) (Snapshot, bool) {
+	i.mx.RLock()
+	defer i.mx.RUnlock()
+
+	c, ok := i.containers[id]
+	if !ok {
+		return nil, false
+	}
+
+	return i.snapshot(c), true
+}
+
+// ItemIndices Returns the item index of every object, 0 for objects which were never seen
+func (i *Inventory) ItemIndices(objects []int) []int {
+	i.mx.RLock()
+	defer i.mx.RUnlock()
+
+	indices := make([]int, len(objects))
+	for n, object := range objects {
+		indices[n] = i.objects[object].ItemIndex
+	}
+
+	return indices
+}
+
+// Diff Returns the items whose quantity changed, ordered by item index
+func Diff(before Snapshot, after Snapshot) []Change {
+	changes := make([]Change, 0)
+
+	for index, quantity := range before {
+		if after[index] != quantity {
+			changes = append(changes, Change{ItemIndex: index, Before: quantity, After: after[index], Delta: after[index] - quantity})
+		}
+	}
+
+	for index, quantity := range after {
+		if _, ok := before[index]; !ok {
+			changes = append(changes, Change{ItemIndex: index, After: quantity, Delta: quantity})
+		}
+	}
+
+	sort.Slice(changes, func(a, b int) bool {
+		return changes[a].ItemIndex < changes[b].ItemIndex
+	})
+
+	return changes
+}
+
+func (i *Inventory) snapshot(c *Container) Snapshot {
+	snapshot := make(Snapshot)
+
+	for _, object := range c.Slots {
+		if object != 0 {
+			stack := i.stack(object)
+			snapshot[stack.ItemIndex] += stack.Quantity
+		}
+	}
+
+	return snapshot
+}
+
+// stack Returns the stack of the object, unseen objects count as a single unknown item
+func (i *Inventory) stack(object int) Stack {
+	stack, ok := i.objects[object]
+	if !ok {
+		return Stack{Object: object, Quantity: 1}
+	}
+
+	return stack
+}
+
+// remove Empties every slot holding the object, an object is only ever in one place
+func (i *Inventory) remove(object int) {
+	for _, c := range i.containers {
+		for slot, o := range c.Slots {
+			if o == object {
+				c.Slots[slot] = 0
+			}
+		}
+	}
+}
+
+func (c *Container) grow(slot int) {
+	if slot >= len(c.Slots) {
+		c.Slots = append(c.Slots, make([]int, slot+1-len(c.Slots))...)
+	}
+}
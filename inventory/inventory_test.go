@@ -0,0 +1,72 @@
+package inventory
+
+import (
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+)
+
+func TestInventory(t *testing.T) {
+	bag, chest := uuid.New(), uuid.New()
+	inv := New()
+
+	inv.AddObject(101, 1204, 1)
+	inv.AddObject(102, 7, 20)
+	inv.AddObject(103, 7, 5)
+	inv.AddObject(201, 900, 1)
+
+	inv.Attach(1, bag, []int{101, 0, 102}, 4)
+	inv.Attach(2, chest, []int{201, 103}, 2)
+
+	// Looting the chest into the empty bag slot, then swapping two bag slots
+	inv.Put(201, bag, 1)
+	inv.Move(0, bag, 3, bag)
+
+	stacks, ok := inv.Contents(bag)
+	want := []Stack{{201, 900, 1}, {102, 7, 20}, {101, 1204, 1}}
+	if !ok || !reflect.DeepEqual(stacks, want) {
+		t.Errorf("bag holds %v, want %v", stacks, want)
+	}
+
+	if stacks, _ := inv.Contents(chest); !reflect.DeepEqual(stacks, []Stack{{103, 7, 5}}) {
+		t.Errorf("chest holds %v", stacks)
+	}
+
+	// Dragging the rest of the chest onto the bag swaps it with the bag stack
+	inv.Move(1, chest, 2, bag)
+
+	changes := inv.Detach(chest)
+	wantChanges := []Change{
+		{ItemIndex: 7, Before: 5, After: 20, Delta: 15},
+		{ItemIndex: 900, Before: 1, After: 0, Delta: -1},
+	}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("chest changes %+v, want %+v", changes, wantChanges)
+	}
+
+	if changes := inv.Detach(chest); changes != nil {
+		t.Errorf("detaching twice reported %+v", changes)
+	}
+
+	if indices := inv.ItemIndices([]int{101, 999}); !reflect.DeepEqual(indices, []int{1204, 0}) {
+		t.Errorf("item indices %v", indices)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := Snapshot{1: 10, 2: 3}
+	after := Snapshot{1: 10, 2: 1, 3: 4}
+
+	want := []Change{
+		{ItemIndex: 2, Before: 3, After: 1, Delta: -2},
+		{ItemIndex: 3, Before: 0, After: 4, Delta: 4},
+	}
+
+	if got := Diff(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff = %+v, want %+v", got, want)
+	}
+
+	if got := Diff(before, before); len(got) != 0 {
+		t.Errorf("diff of the same snapshot %+v", got)
+	}
+}
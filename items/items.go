@@ -0,0 +1,245 @@
+// Package items Resolves the numeric item indices the game sends into item names, using the formatted item dump
+// (items.json or items.txt) or the raw items.xml of ao-bin-dumps.
+package items
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var UnsupportedFormat = errors.New("Unsupported item dump, expected .json, .txt or .xml")
+
+const DefaultLanguage = "EN-US"
+
+type Item struct {
+	Index       int    `json:"index"`
+	UniqueName  string `json:"uniqueName"`
+	Name        string `json:"name"`
+	Tier        int    `json:"tier"`
+	Enchantment int    `json:"enchantment"`
+}
+
+// Database An index to item table, a nil Database knows no items so callers don't need to check whether one was
+// loaded.
+type Database struct {
+	items map[int]Item
+}
+
+type jsonItem struct {
+	Index          string            `json:"Index"`
+	UniqueName     string            `json:"UniqueName"`
+	LocalizedNames map[string]string `json:"LocalizedNames"`
+}
+
+// Load Reads the dump at path, language picks the localized name in items.json
+func Load(path string, language string) (*Database, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ReadJSON(file, language)
+	case ".txt":
+		return ReadText(file)
+	case ".xml":
+		return ReadXML(file)
+	default:
+		return nil, UnsupportedFormat
+	}
+}
+
+// ReadJSON Reads formatted/items.json, items without a name in language fall back to EN-US
+func ReadJSON(r io.Reader, language string) (*Database, error) {
+	var dump []jsonItem
+	if err := json.NewDecoder(r).Decode(&dump); err != nil {
+		return nil, err
+	}
+
+	d := &Database{items: make(map[int]Item, len(dump))}
+
+	for _, entry := range dump {
+		index, err := strconv.Atoi(entry.Index)
+		if err != nil {
+			return nil, fmt.Errorf("item %s: %w", entry.UniqueName, err)
+		}
+
+		name := entry.LocalizedNames[language]
+		if name == "" {
+			name = entry.LocalizedNames[DefaultLanguage]
+		}
+
+		d.add(index, entry.UniqueName, name)
+	}
+
+	return d, nil
+}
+
+// ReadText Reads formatted/items.txt, one "index: UNIQUE_NAME : Name" per line with the name being optional
+func ReadText(r io.Reader) (*Database, error) {
+	d := &Database{items: make(map[int]Item)}
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		fields := strings.SplitN(text, ":", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: %q", line, text)
+		}
+
+		index, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		name := ""
+		if len(fields) == 3 {
+			name = strings.TrimSpace(fields[2])
+		}
+
+		d.add(index, strings.TrimSpace(fields[1]), name)
+	}
+
+	return d, scanner.Err()
+}
+
+// ReadXML Reads the raw items.xml, which carries neither indices nor localized names. Items are numbered in
+// document order starting at 1, every enchanted variant right after its base item, which is how the formatted
+// dumps number them. Names are left empty so Name falls back to the unique name.
+func ReadXML(r io.Reader) (*Database, error) {
+	d := &Database{items: make(map[int]Item)}
+	decoder := xml.NewDecoder(r)
+
+	index, depth := 1, 0
+	uniqueName := ""
+	levels := make([]int, 0)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return d, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+
+			// Children of the root are the items, shopcategories and the like have no unique name
+			if depth == 2 {
+				uniqueName = attr(t, "uniquename")
+				levels = levels[:0]
+			}
+
+			if depth > 2 && uniqueName != "" && t.Name.Local == "enchantment" {
+				level, err := strconv.Atoi(attr(t, "enchantmentlevel"))
+				if err != nil {
+					return nil, fmt.Errorf("item %s: %w", uniqueName, err)
+				}
+
+				levels = append(levels, level)
+			}
+
+		case xml.EndElement:
+			if depth == 2 && uniqueName != "" {
+				d.add(index, uniqueName, "")
+				index++
+
+				for _, level := range levels {
+					if level > 0 {
+						d.add(index, uniqueName+"@"+strconv.Itoa(level), "")
+						index++
+					}
+				}
+
+				uniqueName = ""
+			}
+
+			depth--
+		}
+	}
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+func (d *Database) Lookup(index int) (Item, bool) {
+	if d == nil {
+		return Item{}, false
+	}
+
+	item, ok := d.items[index]
+	return item, ok
+}
+
+// Name Returns the localized name, the unique name when there is none and the raw index for unknown items
+func (d *Database) Name(index int) string {
+	item, ok := d.Lookup(index)
+
+	switch {
+	case !ok:
+		return "#" + strconv.Itoa(index)
+	case item.Name != "":
+		return item.Name
+	default:
+		return item.UniqueName
+	}
+}
+
+func (d *Database) Len() int {
+	if d == nil {
+		return 0
+	}
+
+	return len(d.items)
+}
+
+func (d *Database) add(index int, uniqueName string, name string) {
+	tier, enchantment := parseUniqueName(uniqueName)
+
+	d.items[index] = Item{
+		Index:       index,
+		UniqueName:  uniqueName,
+		Name:        name,
+		Tier:        tier,
+		Enchantment: enchantment,
+	}
+}
+
+// parseUniqueName Reads tier and enchantment from names like T4_BAG@2, names without them return 0
+func parseUniqueName(uniqueName string) (tier int, enchantment int) {
+	name, level, found := strings.Cut(uniqueName, "@")
+	if found {
+		enchantment, _ = strconv.Atoi(level)
+	}
+
+	if len(name) > 1 && name[0] == 'T' {
+		prefix, _, _ := strings.Cut(name[1:], "_")
+		tier, _ = strconv.Atoi(prefix)
+	}
+
+	return tier, enchantment
+}
@@ -0,0 +1,127 @@
+package items
+
+import (
+	"strings"
+	"testing"
+)
+
+const itemsJSON = `[
+	{"LocalizationNameVariable": "@ITEMS_T4_BAG", "LocalizedNames": {"EN-US": "Adept's Bag", "DE-DE": "Tasche des Adepten"}, "Index": "1204", "UniqueName": "T4_BAG"},
+	{"LocalizedNames": {"EN-US": "Adept's Bag"}, "Index": "1205", "UniqueName": "T4_BAG@1"},
+	{"LocalizedNames": null, "Index": "0", "UniqueName": "UNIQUE_HIDEOUT"}
+]`
+
+const itemsText = `   0: UNIQUE_HIDEOUT                                                  : Hideout Construction Kit
+1204: T4_BAG                                                          : Adept's Bag
+1207: T8_2H_CLAYMORE@3
+
+`
+
+func TestReadJSON(t *testing.T) {
+	db, err := ReadJSON(strings.NewReader(itemsJSON), "DE-DE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		index int
+		want  Item
+	}{
+		{1204, Item{Index: 1204, UniqueName: "T4_BAG", Name: "Tasche des Adepten", Tier: 4}},
+		{1205, Item{Index: 1205, UniqueName: "T4_BAG@1", Name: "Adept's Bag", Tier: 4, Enchantment: 1}},
+		{0, Item{Index: 0, UniqueName: "UNIQUE_HIDEOUT"}},
+	}
+
+	for _, tt := range tests {
+		if got, ok := db.Lookup(tt.index); !ok || got != tt.want {
+			t.Errorf("Lookup(%d) = %+v, %v, want %+v", tt.index, got, ok, tt.want)
+		}
+	}
+}
+
+func TestReadText(t *testing.T) {
+	db, err := ReadText(strings.NewReader(itemsText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if db.Len() != 3 {
+		t.Fatalf("%d items, want 3", db.Len())
+	}
+
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "Hideout Construction Kit"},
+		{1204, "Adept's Bag"},
+		{1207, "T8_2H_CLAYMORE@3"},
+		{99, "#99"},
+	}
+
+	for _, tt := range tests {
+		if got := db.Name(tt.index); got != tt.want {
+			t.Errorf("Name(%d) = %q, want %q", tt.index, got, tt.want)
+		}
+	}
+
+	if item, _ := db.Lookup(1207); item.Tier != 8 || item.Enchantment != 3 {
+		t.Errorf("T8_2H_CLAYMORE@3 is tier %d enchantment %d", item.Tier, item.Enchantment)
+	}
+
+	if _, err := ReadText(strings.NewReader("T4_BAG\n")); err == nil {
+		t.Error("line without index was accepted")
+	}
+}
+
+const itemsXML = `<?xml version="1.0" encoding="utf-8"?>
+<items xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <shopcategories>
+    <shopcategory id="bags" value="Bags" />
+  </shopcategories>
+  <hideoutitem uniquename="UNIQUE_HIDEOUT" tier="1" />
+  <equipmentitem uniquename="T4_BAG" tier="4">
+    <craftingrequirements silver="0" />
+    <enchantments>
+      <enchantment enchantmentlevel="1" itempower="800">
+        <upgraderequirements />
+      </enchantment>
+      <enchantment enchantmentlevel="2" itempower="900" />
+    </enchantments>
+  </equipmentitem>
+  <weapon uniquename="T8_2H_CLAYMORE" tier="8" />
+</items>`
+
+func TestReadXML(t *testing.T) {
+	db, err := ReadXML(strings.NewReader(itemsXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"UNIQUE_HIDEOUT", "T4_BAG", "T4_BAG@1", "T4_BAG@2", "T8_2H_CLAYMORE"}
+	if db.Len() != len(want) {
+		t.Fatalf("%d items, want %d", db.Len(), len(want))
+	}
+
+	for i, name := range want {
+		if got := db.Name(i + 1); got != name {
+			t.Errorf("Name(%d) = %q, want %q", i+1, got, name)
+		}
+	}
+
+	if item, _ := db.Lookup(4); item.Tier != 4 || item.Enchantment != 2 {
+		t.Errorf("T4_BAG@2 is tier %d enchantment %d", item.Tier, item.Enchantment)
+	}
+
+	if _, err := ReadXML(strings.NewReader("<items><weapon uniquename=\"T4_MAIN_SWORD\">")); err == nil {
+		t.Error("truncated dump was accepted")
+	}
+}
+
+func TestNilDatabase(t *testing.T) {
+	var db *Database
+
+	if _, ok := db.Lookup(1); ok || db.Name(1) != "#1" || db.Len() != 0 {
+		t.Error("nil database knows items")
+	}
+}
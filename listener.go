@@ -0,0 +1,523 @@
+package main
+
+import (
+	"M00DSWINGS/audit"
+	"M00DSWINGS/combat"
+	"M00DSWINGS/identity"
+	"M00DSWINGS/inventory"
+	"M00DSWINGS/lootchest"
+	"M00DSWINGS/lootsplit"
+	"M00DSWINGS/meter"
+	"M00DSWINGS/protocol"
+	"M00DSWINGS/protocol/packets"
+	"log"
+	"sync"
+	"time"
+)
+
+// Listener Applies decoded packets to the session and forwards them to the server. GameDataManager keeps the
+// characters and parties, every other subsystem is handed to the listener by main.
+type Listener struct {
+	game       *GameDataManager
+	ws         *WebSocketClient
+	identities *identity.Registry
+	inventory  *inventory.Inventory
+	chests     *lootchest.Tracker
+	audit      *audit.Log
+	meter      *meter.Meter
+	combat     *combat.Meter
+	clock      *clock
+}
+
+// Handle captured is when the packet was captured, hits are timed with it so replays measure fights like they were
+// fought
+func (l *Listener) Handle(captured time.Time, data interface{}) {
+	game, ws := l.game, l.ws
+
+	l.clock.set(captured)
+	l.meter.Start(captured)
+
+	switch d := data.(type) {
+	case *packets.OpJoinGame:
+		// The game joins every zone it loads, object IDs of the zone left behind are dropped before the new one's
+		// are applied
+		l.identities.Clear()
+
+		log.Printf("Joined game with Character ID: %s, Name: %s, Guild: %s, Alliance: %s", d.CharacterID, d.CharacterName, d.GuildName, d.AllianceName)
+		if err := game.Initialize(d.CharacterID, d.CharacterName, d.GuildName, d.AllianceName); err != nil {
+			log.Println(err)
+			return
+		}
+
+		if err := ws.Initialize(d.CharacterID, d.CharacterName, d.GuildName, d.AllianceName); err != nil {
+			log.Fatal(err)
+		}
+
+		l.changeCluster(d.Cluster, captured)
+		l.identities.Add(d.ObjectId, d.CharacterID, d.CharacterName)
+
+	case *packets.OpClusterChange:
+		l.changeCluster(d.Cluster, captured)
+
+	case *packets.EvNewCharacter:
+		if err := game.CreateNewChar(d.PlayerUID, d.PlayerName, d.GuildName, d.AllianceName); err != nil {
+			log.Println(err)
+			return
+		}
+
+		l.identities.Add(d.ObjectId, d.PlayerUID, d.PlayerName)
+
+		if err := ws.CreateNewChar(d.PlayerUID, d.PlayerName, d.GuildName, d.AllianceName); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvCharacterStats:
+		game.UpdateSelfStats(d.PlayerName, d.GuildName, d.AllianceName)
+
+		if err := ws.UpdateCharacterStats(d.PlayerName, d.GuildName, d.AllianceName); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvPartySinglePlayerJoined:
+		if err := game.CreateNewChar(d.PlayerUID, d.PlayerName, "", ""); err != nil {
+			log.Println(err)
+			return
+		}
+
+		if err := ws.CreateNewChar(d.PlayerUID, d.PlayerName, "", ""); err != nil {
+			log.Println(err)
+		}
+
+		if !game.AddPartyMember(d.PlayerUID, captured) {
+			return
+		}
+
+		l.recordAudit(audit.KindJoined, d.PlayerName, captured)
+
+		if err := ws.AddPartyPlayer(d.PlayerUID); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvPartyJoined:
+		for i, playerUsername := range d.PlayerUsernames {
+			if i >= len(d.PlayersUuid) {
+				break
+			}
+
+			if err := game.CreateNewChar(d.PlayersUuid[i], playerUsername, "", ""); err != nil {
+				log.Println(err)
+				continue
+			}
+
+			if err := ws.CreateNewChar(d.PlayersUuid[i], playerUsername, "", ""); err != nil {
+				log.Println(err)
+			}
+		}
+
+		party := game.JoinParty(d.PartyLeader, d.PlayersUuid, captured)
+
+		if err := ws.JoinParty(party.PartyOwner, party.Members.Values()); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvPartyLeft:
+		if game.IsCurrentUser(d.PlayerUID) {
+			l.reportLootSplit(captured)
+		}
+
+		if !game.RemovePartyMember(d.PlayerUID, captured) {
+			return
+		}
+
+		if err := ws.RemovePartyPlayer(d.PlayerUID); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvPartyDisbanded:
+		l.reportLootSplit(captured)
+
+		if !game.DisbandCurrentParty(captured) {
+			return
+		}
+
+		if err := ws.DisbandParty(); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvPartyLeaderChanged:
+		if !game.ChangePartyLeader(d.NewPartyLeader, captured) {
+			return
+		}
+
+		if err := ws.UpdatePartyLeader(d.NewPartyLeader); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvPartySetRoleFlag:
+		if !game.SetPartyRoleFlag(d.PlayerUID, d.Flag) {
+			return
+		}
+
+		if err := ws.UpdatePartyRoleFlag(d.PlayerUID, d.Flag); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvPartyLootSettingChanged:
+		self, ok := game.SetSelfLootSetting(d.LootSetting)
+		if !ok {
+			return
+		}
+
+		if err := ws.UpdatePartyLootSetting(self, d.LootSetting); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.OpInventoryMoveItems:
+		l.inventory.Move(d.FromSlot, d.FromUUID, d.ToSlot, d.ToUUID)
+
+		if err := ws.MoveItems(d.FromSlot, d.FromUUID, d.ToSlot, d.ToUUID); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvInventoryPutItems:
+		l.inventory.Put(d.ObjectId, d.ContainerId, d.SlotId)
+
+		if err := ws.PutItems(d.ObjectId, d.ContainerId, d.SlotId); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvNewLootChest:
+		l.chests.Spawn(d.Id, d.Owner, lootchest.KindChest, game.GetCluster(), captured)
+
+		if err := ws.CreateNewLootChest(d.Id, d.Owner); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvNewLoot:
+		l.chests.Spawn(d.Id, d.Owner, lootchest.KindCorpse, game.GetCluster(), captured)
+
+		if err := ws.CreateNewLoot(d.Id, d.Owner); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvUpdateLootChest:
+		l.chests.Update(d.Id, captured)
+
+		if err := ws.UpdateLootChest(d.Id); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvOtherGrabbedLoot:
+		l.chests.Grab(d.LootedFromName, lootchest.Take{Looter: d.LooterByName, Silver: d.IsSilver, ItemIndex: d.ItemIndex, Quantity: d.Quantity, At: captured})
+		game.RecordGrabbedLoot(d.LootedFromName, d.LooterByName, d.IsSilver, d.ItemIndex, d.Quantity, captured)
+
+		value := game.LootValue(d.IsSilver, d.ItemIndex, d.Quantity)
+		log.Printf("%s looted %dx %s from %s, worth %d silver", d.LooterByName, d.Quantity, game.LootName(d.IsSilver, d.ItemIndex), d.LootedFromName, value)
+
+		if err := ws.OtherGrabLoot(d.LootedFromName, d.LooterByName, d.IsSilver, d.ItemIndex, d.Quantity, value); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvNewSimpleItem:
+		l.inventory.AddObject(d.Id, d.ItemIndex, d.Quantity)
+
+		// Every sample is a single item, so the estimate of a stack isn't known to be per item
+		if d.Quantity == 1 {
+			game.UpdateMarketValue(d.ItemIndex, d.MarketValue)
+		}
+
+		if err := ws.NewSimpleItem(d.Id, d.ItemIndex, d.Quantity, game.LootValue(false, d.ItemIndex, d.Quantity)); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvAttachItemContainer:
+		l.inventory.Attach(d.Id, d.ContainerUUID, d.Items, d.Slots)
+
+		contents, _ := l.inventory.Contents(d.ContainerUUID)
+		l.chests.Attach(d.Id, d.ContainerUUID, contents, game.GetSelfUsername())
+
+		if err := ws.AttachItemContainer(d.Id, d.ContainerUUID, d.Items, d.Slots, l.inventory.ItemIndices(d.Items)); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvDetachItemContainer:
+		changes := l.inventory.Detach(d.ContainerUUID)
+		for _, change := range changes {
+			log.Printf("Container %s: %+d %s", d.ContainerUUID, change.Delta, game.LootName(false, change.ItemIndex))
+		}
+
+		l.chests.Detach(d.ContainerUUID, changes, game.GetSelfUsername(), captured)
+
+		if err := ws.DetachItemContainer(d.ContainerUUID); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvLeave:
+		l.identities.Leave(int(d.Id))
+
+		chest, ok := l.chests.Despawn(int(d.Id), captured)
+		if !ok {
+			return
+		}
+
+		game.SaveLootChest(chest)
+
+		log.Printf("%s of %s despawned, opened by %v, %d items taken", chest.Kind, chest.Owner, chest.Openers, len(chest.Takes))
+
+		if err := ws.LootChestDespawned(chest); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvHealthUpdate:
+		l.recordHit(combat.Hit{At: captured, Causer: d.CauserId, Target: d.ObjectId, Delta: float64(d.Change)})
+
+	case *packets.EvUpdateFame:
+		if !l.isSelf(d.ObjectId) {
+			return
+		}
+
+		l.meter.AddFame(protocol.DecodeFixedPoint(d.Gained))
+
+		if err := ws.SessionMeter(l.meter.Summary(captured), false); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvUpdateMoney:
+		if !l.isSelf(d.ObjectId) {
+			return
+		}
+
+		l.meter.SetPurse(protocol.DecodeFixedPoint(d.Silver))
+
+	case *packets.EvTakeSilver:
+		if !l.isSelf(d.ObjectId) {
+			return
+		}
+
+		l.meter.AddSilver(protocol.DecodeFixedPoint(d.Amount))
+
+		if err := ws.SessionMeter(l.meter.Summary(captured), false); err != nil {
+			log.Println(err)
+		}
+
+	case *packets.EvPartyReadyCheck:
+		if err := ws.PartyReadCheck(d.Members, d.Status); err != nil {
+			log.Println(err)
+		}
+
+	default:
+		log.Println(data)
+	}
+}
+
+// resync Sends the characters and party of the local state, initialize is sent by the client itself
+func (l *Listener) resync() {
+	game, ws := l.game, l.ws
+
+	if cluster := game.GetCluster(); cluster != "" {
+		if err := ws.ChangeCluster(cluster); err != nil {
+			log.Println(err)
+		}
+	}
+
+	leader, members, ok := game.CurrentPartyMembers()
+	if !ok {
+		return
+	}
+
+	for _, id := range members {
+		char, ok := game.GetCharacter(id)
+		if !ok {
+			continue
+		}
+
+		if err := ws.CreateNewChar(char.ID, char.Name, char.Guild, char.Alliance); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if err := ws.JoinParty(leader, members); err != nil {
+		log.Println(err)
+	}
+
+	roles, lootSettings := game.CurrentPartyRoles()
+
+	for id, flag := range roles {
+		if err := ws.UpdatePartyRoleFlag(id, flag); err != nil {
+			log.Println(err)
+		}
+	}
+
+	for id, setting := range lootSettings {
+		if err := ws.UpdatePartyLootSetting(id, setting); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// reportSession Logs the fame and silver of the session so far and sends it as the session summary, the running
+// fight ends with it
+func (l *Listener) reportSession() {
+	if fight, ok := l.combat.Close(); ok {
+		l.reportFight(fight)
+	}
+
+	summary := l.meter.Summary(l.clock.now())
+
+	log.Printf("Session of %s: %.0f fame (%.0f/h), %.0f silver (%.0f/h), purse %+.0f", summary.Session.Duration.Round(time.Second),
+		summary.Session.Fame, summary.Session.FamePerHour, summary.Session.Silver, summary.Session.SilverPerHour, summary.Session.Balance)
+	for _, cluster := range summary.Clusters {
+		log.Printf("  %s for %s: %.0f fame (%.0f/h), %.0f silver (%.0f/h)", cluster.Cluster, cluster.Duration.Round(time.Second),
+			cluster.Fame, cluster.FamePerHour, cluster.Silver, cluster.SilverPerHour)
+	}
+
+	if err := l.ws.SessionMeter(summary, true); err != nil {
+		log.Println(err)
+	}
+}
+
+// recordHit Adds the hit to the combat meter, reports the fight it ended and sends the running fight now and then
+func (l *Listener) recordHit(hit combat.Hit) {
+	hit.Cluster = l.game.GetCluster()
+
+	if fight, ok := l.combat.Record(hit, l.game.Fighters()); ok {
+		l.reportFight(fight)
+	}
+
+	fight, ok := l.combat.Live(hit.At)
+	if !ok {
+		return
+	}
+
+	if err := l.ws.CombatFight(fight, false); err != nil {
+		log.Println(err)
+	}
+}
+
+// expireFight Reports the running fight once the party was out of combat for the idle time, otherwise the last
+// fight before the game goes quiet is only reported with the next one. Registered as an idle callback of the logger,
+// so it runs on the packet goroutine and never alongside Handle
+func (l *Listener) expireFight() {
+	now := l.clock.now()
+	if now.IsZero() {
+		return
+	}
+
+	if fight, ok := l.combat.Expire(now); ok {
+		l.reportFight(fight)
+	}
+}
+
+// reportFight Sends the finished fight and writes its report
+func (l *Listener) reportFight(fight combat.Fight) {
+	log.Printf("Fight of %s in %s with %d members", fight.End.Sub(fight.Start).Round(time.Second), fight.Cluster, len(fight.Members))
+
+	if err := l.ws.CombatFight(fight, true); err != nil {
+		log.Println(err)
+	}
+
+	if fightsDir == "" {
+		return
+	}
+
+	if err := combat.Save(fightsDir, fight); err != nil {
+		log.Printf("Failed to save fight report: %v", err)
+	}
+}
+
+// changeCluster Moves the player to the zone and tells the server, an empty cluster is skipped. Fights don't carry
+// over into another zone.
+func (l *Listener) changeCluster(cluster string, at time.Time) {
+	if !l.game.ChangeCluster(cluster) {
+		return
+	}
+
+	l.meter.Enter(cluster, at)
+
+	if fight, ok := l.combat.Close(); ok {
+		l.reportFight(fight)
+	}
+
+	log.Printf("Entered cluster %s", cluster)
+
+	if err := l.ws.ChangeCluster(cluster); err != nil {
+		log.Println(err)
+	}
+}
+
+// recordAudit Adds the entry to the audit trail, warns about it and forwards it
+func (l *Listener) recordAudit(kind audit.Kind, playerName string, at time.Time) {
+	char, known := l.game.FindCharacter(playerName)
+
+	entry := l.audit.Record(audit.Entry{
+		Kind:       kind,
+		PlayerName: playerName,
+		Guild:      char.Guild,
+		Alliance:   char.Alliance,
+		At:         at,
+	}, known)
+
+	l.game.SaveAuditEntry(entry)
+
+	for _, warning := range entry.Warnings {
+		log.Printf("Party audit %s %s: %s", entry.Kind, entry.PlayerName, warning)
+	}
+
+	if err := l.ws.PartyAudit(entry); err != nil {
+		log.Println(err)
+	}
+}
+
+// isSelf Whether the object is the user's character, fame and silver of everyone else nearby is sent as well
+func (l *Listener) isSelf(object int) bool {
+	who, ok := l.identities.ByObject(object)
+	return ok && l.game.IsCurrentUser(who.ID)
+}
+
+// reportLootSplit Settles the loot of the current party up to at, called right before the user leaves it
+func (l *Listener) reportLootSplit(at time.Time) {
+	report, err := l.game.SplitCurrentParty(lootsplit.Rules{Mode: splitMode, CallerCut: callerCut}, at)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	log.Printf("Loot split (%s): total %d, caller cut %d, ignored %d", report.Mode, report.Total, report.CallerCut, report.Ignored)
+	for _, entry := range report.Entries {
+		log.Printf("  %s: share %d, holding %d, balance %d", entry.Name, entry.Share, entry.Holding, entry.Balance)
+	}
+
+	if err := l.ws.LootSplit(report); err != nil {
+		log.Println(err)
+	}
+}
+
+// clock The capture time of the latest packet, between packets it runs on with the wall clock
+type clock struct {
+	mx       *sync.Mutex
+	captured time.Time
+	seen     time.Time
+}
+
+func newClock() *clock {
+	return &clock{mx: new(sync.Mutex)}
+}
+
+func (c *clock) set(captured time.Time) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.captured, c.seen = captured, time.Now()
+}
+
+// now Returns the zero time until the first packet arrived
+func (c *clock) now() time.Time {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.captured.IsZero() {
+		return time.Time{}
+	}
+
+	return c.captured.Add(time.Since(c.seen))
+}
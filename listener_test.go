@@ -0,0 +1,187 @@
+package main
+
+import (
+	"M00DSWINGS/combat"
+	"M00DSWINGS/identity"
+	"M00DSWINGS/inventory"
+	"M00DSWINGS/lootchest"
+	"M00DSWINGS/meter"
+	"M00DSWINGS/pricing"
+	"M00DSWINGS/protocol/packets"
+	"M00DSWINGS/protocol/photon"
+	"encoding/json"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestListenerCountsOwnSilverOnly(t *testing.T) {
+	start := time.Now().UTC()
+	self, other := uuid.New(), uuid.New()
+
+	identities := identity.NewRegistry()
+	game := NewGameDataManager(identities)
+	if err := game.Initialize(self, "Anna", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	identities.Add(100, self, "Anna")
+	identities.Add(200, other, "Bob")
+
+	listener := &Listener{
+		game:       game,
+		ws:         NewWebSocketClient(""),
+		identities: identities,
+		meter:      meter.New(start),
+		clock:      newClock(),
+	}
+
+	listener.Handle(start, &packets.EvTakeSilver{ObjectId: 200, Amount: 5000000})
+	listener.Handle(start, &packets.EvUpdateFame{ObjectId: 200, Gained: 1000000})
+
+	if got := listener.meter.Summary(start).Session; got.Silver != 0 || got.Fame != 0 {
+		t.Fatalf("another player's pickup counted: %.0f silver, %.0f fame", got.Silver, got.Fame)
+	}
+
+	// Fractions of a silver add up over a session, they aren't cut off
+	listener.Handle(start, &packets.EvTakeSilver{ObjectId: 100, Amount: 5000050})
+
+	if got := listener.meter.Summary(start).Session.Silver; got != 500.005 {
+		t.Errorf("own pickup counted as %v silver, want 500.005", got)
+	}
+}
+
+func TestListenerKeepsObjectsOnClusterChange(t *testing.T) {
+	start := time.Now().UTC()
+	identities := identity.NewRegistry()
+
+	listener := &Listener{
+		game:       NewGameDataManager(identities),
+		ws:         NewWebSocketClient(""),
+		identities: identities,
+		meter:      meter.New(start),
+		combat: combat.New(30*time.Second, time.Second, func(int) (combat.Combatant, bool) {
+			return combat.Combatant{}, false
+		}),
+		clock: newClock(),
+	}
+
+	// The new zone announces its players before the response of the cluster change arrives
+	identities.Add(300, uuid.New(), "Bob")
+	listener.Handle(start, &packets.OpClusterChange{Cluster: "4000"})
+
+	if _, ok := identities.ByObject(300); !ok || listener.game.GetCluster() != "4000" {
+		t.Error("cluster change dropped the objects of the new zone")
+	}
+}
+
+func TestListenerValuesGrabbedSilver(t *testing.T) {
+	start := time.Now().UTC()
+	identities := identity.NewRegistry()
+	ws := NewWebSocketClient("")
+
+	listener := &Listener{
+		game:       NewGameDataManager(identities),
+		ws:         ws,
+		identities: identities,
+		chests:     lootchest.NewTracker(),
+		meter:      meter.New(start),
+		clock:      newClock(),
+	}
+
+	// SILVER: map[0:6436 2:Triky313 3:true 5:1550115 252:256], the sample in protocol/enums/events.go
+	var grabbed packets.EvOtherGrabbedLoot
+	params := photon.ReliableMessageParamaters{0: int16(6436), 2: "Triky313", 3: true, 5: int32(1550115), 252: int16(256)}
+	if err := packets.Decode(params, &grabbed); err != nil {
+		t.Fatal(err)
+	}
+
+	listener.Handle(start, &grabbed)
+
+	var message struct {
+		Action string `json:"action"`
+		Value  int64  `json:"value"`
+	}
+	if err := json.Unmarshal(<-ws.sendChan, &message); err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Action != "other_grab_loot" || message.Value != 155 {
+		t.Errorf("%s worth %d silver, want other_grab_loot worth 155", message.Action, message.Value)
+	}
+}
+
+func TestListenerStoresMarketValueOfNewItems(t *testing.T) {
+	start := time.Now().UTC()
+	identities := identity.NewRegistry()
+	game := NewGameDataManager(identities)
+
+	market, err := pricing.NewCache("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.RegisterPrices(market, market)
+
+	listener := &Listener{
+		game:       game,
+		ws:         NewWebSocketClient(""),
+		identities: identities,
+		inventory:  inventory.New(),
+		meter:      meter.New(start),
+		clock:      newClock(),
+	}
+
+	// map[0:505 1:7006 2:1 3:true 4:29033970 252:27], the sample in protocol/enums/events.go
+	var item packets.EvNewSimpleItem
+	params := photon.ReliableMessageParamaters{0: int16(505), 1: int16(7006), 2: int8(1), 3: true, 4: int32(29033970), 252: int8(27)}
+	if err := packets.Decode(params, &item); err != nil {
+		t.Fatal(err)
+	}
+
+	listener.Handle(start, &item)
+
+	if got := game.LootValue(false, 7006, 2); got != 5806 {
+		t.Errorf("two items worth %d silver, want 5806", got)
+	}
+}
+
+func TestListenerTimesSessionByCapture(t *testing.T) {
+	// A replayed capture happened long before the listener runs
+	captured := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	self, member := uuid.New(), uuid.New()
+
+	identities := identity.NewRegistry()
+	game := NewGameDataManager(identities)
+	if err := game.Initialize(self, "Anna", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	listener := &Listener{
+		game:       game,
+		ws:         NewWebSocketClient(""),
+		identities: identities,
+		meter:      meter.New(time.Time{}),
+		combat: combat.New(30*time.Second, time.Second, func(int) (combat.Combatant, bool) {
+			return combat.Combatant{}, false
+		}),
+		clock: newClock(),
+	}
+
+	listener.Handle(captured, &packets.OpClusterChange{Cluster: "4000"})
+	listener.Handle(captured.Add(10*time.Minute), &packets.EvPartyJoined{
+		PartyLeader:     self,
+		PlayersUuid:     []uuid.UUID{self, member},
+		PlayerUsernames: []string{"Anna", "Bob"},
+	})
+
+	summary := listener.meter.Summary(captured.Add(time.Hour))
+	if !summary.Start.Equal(captured) || summary.Session.Duration != time.Hour {
+		t.Errorf("session from %v for %v, want from %v for an hour", summary.Start, summary.Session.Duration, captured)
+	}
+
+	for _, entry := range game.CurrentParty.History {
+		if !entry.Timestamp.Equal(captured.Add(10 * time.Minute)) {
+			t.Errorf("%v of %v at %v, want the capture time", entry.Action, entry.User, entry.Timestamp)
+		}
+	}
+}
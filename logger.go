@@ -3,39 +3,51 @@ package main
 import (
 	"M00DSWINGS/protocol"
 	"M00DSWINGS/protocol/enums"
+	"M00DSWINGS/protocol/packets"
 	"M00DSWINGS/protocol/photon"
+	"M00DSWINGS/scenario"
 	"errors"
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 	"github.com/google/gopacket/pcap"
-	"github.com/google/uuid"
 	"log"
 	"reflect"
-	"runtime/debug"
-	"strconv"
-	"strings"
 	"sync"
+	"time"
 )
 
+const photonFilter = "udp and (dst port 5056 or src port 5056)"
+
 type Logger struct {
-	device     pcap.Interface
-	events     map[enums.EventType]reflect.Type
-	listeners  []func(interface{})
-	disconnect []func()
-	operations map[enums.OperationType]reflect.Type
-	mx         *sync.Mutex
-	fragments  *photon.FragmentBuffer
+	device       pcap.Interface
+	events       map[enums.EventType]reflect.Type
+	listeners    []func(time.Time, interface{})
+	disconnect   []func()
+	idle         []func()
+	operations   map[enums.OperationType]reflect.Type
+	responses    map[enums.OperationType]reflect.Type
+	mx           *sync.Mutex
+	fragments    *photon.FragmentBuffer
+	recorder     *Recorder
+	deserializer photon.Deserializer
+	stop         chan struct{}
+	stopOnce     *sync.Once
 }
 
 func NewLogger(device pcap.Interface) *Logger {
 	return &Logger{
-		device:     device,
-		disconnect: make([]func(), 0),
-		listeners:  make([]func(interface{}), 0),
-		operations: make(map[enums.OperationType]reflect.Type),
-		events:     make(map[enums.EventType]reflect.Type),
-		mx:         new(sync.Mutex),
-		fragments:  photon.NewFragmentBuffer(),
+		device:       device,
+		disconnect:   make([]func(), 0),
+		idle:         make([]func(), 0),
+		listeners:    make([]func(time.Time, interface{}), 0),
+		operations:   make(map[enums.OperationType]reflect.Type),
+		responses:    make(map[enums.OperationType]reflect.Type),
+		events:       make(map[enums.EventType]reflect.Type),
+		mx:           new(sync.Mutex),
+		fragments:    photon.NewFragmentBuffer(),
+		deserializer: photon.NewAutoDeserializer(photon.Protocol16{}, photon.Protocol18{}),
+		stop:         make(chan struct{}),
+		stopOnce:     new(sync.Once),
 	}
 }
 
@@ -45,7 +57,78 @@ func (e *Logger) ListenAndServe() {
 		panic(err)
 	}
 
-	if err := handle.SetBPFFilter("udp and (dst port 5056 or src port 5056)"); err != nil {
+	defer handle.Close()
+
+	e.serve(handle, false)
+}
+
+// ReplayAndServe Feeds a pcap/pcapng capture file through the same pipeline as ListenAndServe.
+// When realtime is set, packets are delivered with the delays they were captured with,
+// otherwise the file is processed as fast as possible.
+func (e *Logger) ReplayAndServe(path string, realtime bool) error {
+	handle, err := pcap.OpenOffline(path)
+	if err != nil {
+		return err
+	}
+
+	defer handle.Close()
+
+	e.serve(handle, realtime)
+
+	return nil
+}
+
+// PlayAndServe Hands the commands of a simulator scenario straight to the listeners, without a socket or a capture
+// file in between
+func (e *Logger) PlayAndServe(path string) error {
+	s, err := scenario.Load(path)
+	if err != nil {
+		return err
+	}
+
+	return s.Play(loggerEmitter{logger: e}, photon.DefaultFragmentSize, nil)
+}
+
+// loggerEmitter Emits scenario commands into the logger
+type loggerEmitter struct {
+	logger *Logger
+}
+
+func (e loggerEmitter) Emit(at time.Time, command photon.Command) error {
+	e.logger.handleCommand(at, command)
+	return nil
+}
+
+func (e loggerEmitter) Close() error {
+	return nil
+}
+
+// Stop Makes ListenAndServe and ReplayAndServe return once the packet being handled is done, so the deferred
+// cleanup of the caller runs
+func (e *Logger) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stop)
+	})
+}
+
+// RegisterRecorder Makes every packet matching the Photon filter be written to the recorder as well.
+func (e *Logger) RegisterRecorder(r *Recorder) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	e.recorder = r
+}
+
+// RegisterDeserializer Replaces the Photon protocol deserializer, auto detection is used by default.
+func (e *Logger) RegisterDeserializer(d photon.Deserializer) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	e.deserializer = d
+}
+
+func (e *Logger) serve(handle *pcap.Handle, realtime bool) {
+	if err := handle.SetBPFFilter(photonFilter); err != nil {
 		log.Fatal(err)
 	}
 
@@ -54,22 +137,64 @@ func (e *Logger) ListenAndServe() {
 		layers.RegisterTCPPortLayerType(layers.TCPPort(port), photon.LayerType)
 	}
 
-	defer handle.Close()
-
 	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
 	packetSource.NoCopy = true // more performance
 
-	for packet := range packetSource.Packets() {
+	if e.recorder != nil {
+		e.recorder.SetLinkType(handle.LinkType())
+	}
+
+	var last time.Time
+
+	source := packetSource.Packets()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		var packet gopacket.Packet
+
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			e.handleIdle()
+			continue
+		case p, ok := <-source:
+			if !ok {
+				return
+			}
+
+			packet = p
+		}
+
+		if realtime {
+			timestamp := packet.Metadata().Timestamp
+			if !last.IsZero() && timestamp.After(last) && !e.wait(timestamp.Sub(last), ticker) {
+				return
+			}
+
+			last = timestamp
+		}
+
+		if e.recorder != nil {
+			if err := e.recorder.WritePacket(packet.Metadata().CaptureInfo, packet.Data()); err != nil {
+				log.Printf("Failed to record packet: %v", err)
+			}
+		}
+
 		if p, ok := packet.Layer(photon.LayerType).(photon.Layer); ok {
+			at := packet.Metadata().Timestamp.UTC()
 			for _, command := range p.Commands {
-				e.handleCommand(command)
+				e.handleCommand(at, command)
 			}
 		}
 	}
 }
 
-func (e *Logger) handleReliableCommand(cmd *photon.Command) {
-	msg, err := cmd.ReliableMessage()
+// handleReliableCommand at is when the packet carrying the command was captured
+func (e *Logger) handleReliableCommand(at time.Time, cmd *photon.Command) {
+	msg, params, err := e.deserializer.Deserialize(*cmd)
 	if err != nil {
 		if errors.Is(err, protocol.EncryptionNotSupported) {
 			return
@@ -79,18 +204,12 @@ func (e *Logger) handleReliableCommand(cmd *photon.Command) {
 		return
 	}
 
-	params, err := photon.DecodeReliableMessage(msg)
-	if err != nil {
-		//log.Printf("Could not decode reliable message: %v - %v", err, hex.EncodeToString(cmd.Data))
-		return
-	}
-
 	switch msg.Type {
 	case photon.OperationRequest, photon.OperationResponse:
 		if val, ok := params[253]; ok {
-			var opType = enums.OperationType(val.(int16))
+			var opType = enums.OperationType(protocol.DecodeInteger(val))
 
-			e.handleOperation(opType, params)
+			e.handleOperation(at, msg.Type == photon.OperationResponse, opType, params)
 		} else {
 			//log.Printf("ERROR: Could not decode operation: [%d] (%d) (%d) %v", msg.Type,
 			//	msg.ParamaterCount, len(msg.Data),
@@ -102,15 +221,16 @@ func (e *Logger) handleReliableCommand(cmd *photon.Command) {
 		}
 
 		if val, ok := params[252]; ok {
-			var eventType = enums.EventType(val.(int16))
-			e.handleEvent(eventType, params)
+			var eventType = enums.EventType(protocol.DecodeInteger(val))
+			e.handleEvent(at, eventType, params)
 		}
 	default:
 		return
 	}
 }
 
-func (e *Logger) RegisterListeners(f func(event interface{})) {
+// RegisterListeners Listeners get every decoded packet together with the time it was captured at
+func (e *Logger) RegisterListeners(f func(at time.Time, event interface{})) {
 	e.mx.Lock()
 	defer e.mx.Unlock()
 
@@ -124,6 +244,14 @@ func (e *Logger) RegisterOperation(optype enums.OperationType, op interface{}) {
 	e.operations[optype] = reflect.TypeOf(op)
 }
 
+// RegisterResponse Registers an operation only its response is decoded for, requests of it are skipped
+func (e *Logger) RegisterResponse(optype enums.OperationType, op interface{}) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	e.responses[optype] = reflect.TypeOf(op)
+}
+
 func (e *Logger) RegisterEvent(evtype enums.EventType, op interface{}) {
 	e.mx.Lock()
 	defer e.mx.Unlock()
@@ -131,22 +259,28 @@ func (e *Logger) RegisterEvent(evtype enums.EventType, op interface{}) {
 	e.events[evtype] = reflect.TypeOf(op)
 }
 
-func (e *Logger) handleOperation(opType enums.OperationType, params photon.ReliableMessageParamaters) {
-	if _, ok := e.operations[opType]; !ok {
+func (e *Logger) handleOperation(at time.Time, response bool, opType enums.OperationType, params photon.ReliableMessageParamaters) {
+	operation, ok := e.operations[opType]
+	if response {
+		if typ, registered := e.responses[opType]; registered {
+			operation, ok = typ, true
+		}
+	}
+
+	if !ok {
 		return
 	}
 
-	operation := e.operations[opType]
 	value := reflect.New(operation).Interface()
 
 	e.updateData(params, value)
 
 	for _, listener := range e.listeners {
-		listener(value)
+		listener(at, value)
 	}
 }
 
-func (e *Logger) handleEvent(eventType enums.EventType, params photon.ReliableMessageParamaters) {
+func (e *Logger) handleEvent(at time.Time, eventType enums.EventType, params photon.ReliableMessageParamaters) {
 	if _, ok := e.events[eventType]; !ok {
 		return
 	}
@@ -157,120 +291,30 @@ func (e *Logger) handleEvent(eventType enums.EventType, params photon.ReliableMe
 	e.updateData(params, value)
 
 	for _, listener := range e.listeners {
-		listener(value)
+		listener(at, value)
 	}
 }
 
 func (e *Logger) updateData(params photon.ReliableMessageParamaters, input any) {
-	if params == nil || input == nil {
-		return
-	}
-
-	valueOf := reflect.ValueOf(input)
-	typeOf := reflect.TypeOf(input)
-
-	defer func() {
-		if r := recover(); r != nil {
-			debug.PrintStack()
-			log.Println(input, params)
-			log.Printf("Input %s data update failed: %v", typeOf.String(), r)
-		}
-	}()
-
-	val := valueOf.Elem()
-	typ := val.Type()
-
-	if !val.IsValid() {
-		return
-	}
-
-	if typeOf.Kind() == reflect.Func {
+	if reflect.TypeOf(input).Elem().Kind() == reflect.Func {
 		log.Println("Used func instead of object to register event/operation", params)
 		return
 	}
 
-	if m := valueOf.MethodByName("Decode"); m.IsValid() {
-		m.Call([]reflect.Value{reflect.ValueOf(params)})
-		return
-	}
-
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		target := typ.Field(i)
-
-		if _, ok := target.Tag.Lookup("albion"); !ok {
-			continue
-		}
-
-		if !field.CanSet() {
-			log.Printf("Cannot set field %s", target.Name)
-			continue
-		}
-
-		tags := strings.Split(target.Tag.Get("albion"), ",")
-
-		for _, tag := range tags {
-			num, err := strconv.Atoi(strings.TrimSpace(tag))
-			if err != nil {
-				log.Printf("Invalid tag value %s for field %s: %v", tag, target.Name, err)
-				continue
-			}
-
-			if v, ok := params[uint8(num)]; ok {
-				if field.Kind() == reflect.Int64 {
-					v = protocol.DecodeInt64(v)
-				}
-				if field.Kind() == reflect.Int {
-					v = protocol.DecodeInteger(v)
-				}
-
-				if field.Type() == reflect.TypeOf([]int8{}) ||
-					field.Type() == reflect.TypeOf([]int{}) ||
-					field.Type() == reflect.TypeOf([]int64{}) {
-					v = protocol.DecodeIntegers(v)
-				}
-
-				if field.Type() == reflect.TypeOf(uuid.Nil) {
-					v = protocol.DecodeCharacterID(v.([]int8))
-				}
-
-				if field.Type() == reflect.TypeOf([]uuid.UUID{}) {
-					var uui []uuid.UUID
-
-					for _, id := range v.([][]int8) {
-						uui = append(uui, protocol.DecodeCharacterID(id))
-					}
-
-					v = uui
-				}
-
-				if field.Kind() == reflect.String {
-					if data, ok := target.Tag.Lookup("not-contains"); ok {
-						if strings.Contains(v.(string), data) {
-							continue
-						}
-					}
-				}
-
-				value := reflect.ValueOf(v)
-
-				if field.Type() == value.Type() {
-					field.Set(value)
-					break
-				} else {
-					log.Printf("Type mismatch for field %s.%s: expected %s, got %s", typ.Name(), target.Name, field.Type(), value.Type())
-				}
-
-			}
-		}
+	if err := packets.Decode(params, input); err != nil {
+		log.Printf("Input %T data update failed: %v", input, err)
 	}
 }
 
-func (e *Logger) handleCommand(command photon.Command) {
+func (e *Logger) handleCommand(at time.Time, command photon.Command) {
 	switch command.Type {
 	case photon.SendReliableType:
-		e.handleReliableCommand(&command)
+		e.handleReliableCommand(at, &command)
 	case photon.SendUnreliableType:
+		if len(command.Data) < 4 {
+			return
+		}
+
 		var s = make([]byte, len(command.Data)-4)
 		copy(s, command.Data[4:])
 
@@ -278,7 +322,7 @@ func (e *Logger) handleCommand(command photon.Command) {
 		command.Length -= 4
 		command.Type = 6
 
-		e.handleReliableCommand(&command)
+		e.handleReliableCommand(at, &command)
 	case photon.DisconnectType:
 		e.handleDisconnect()
 	case photon.SendReliableFragmentType:
@@ -289,7 +333,7 @@ func (e *Logger) handleCommand(command photon.Command) {
 
 		result := e.fragments.Offer(msg)
 		if result != nil {
-			e.handleReliableCommand(result)
+			e.handleReliableCommand(at, result)
 		}
 	}
 }
@@ -301,6 +345,41 @@ func (e *Logger) RegisterDisconnect(f func()) {
 	e.disconnect = append(e.disconnect, f)
 }
 
+// RegisterIdle Makes f run every second between packets, on the goroutine which hands packets to the listeners
+func (e *Logger) RegisterIdle(f func()) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	e.idle = append(e.idle, f)
+}
+
+func (e *Logger) handleIdle() {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	for _, f := range e.idle {
+		f()
+	}
+}
+
+// wait Sleeps for the delay of a realtime replay, idle callbacks keep running meanwhile. Returns false when the
+// logger was stopped.
+func (e *Logger) wait(delay time.Duration, ticker *time.Ticker) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return false
+		case <-ticker.C:
+			e.handleIdle()
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
 func (e *Logger) handleDisconnect() {
 	e.mx.Lock()
 	defer e.mx.Unlock()
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"M00DSWINGS/protocol/enums"
+	"M00DSWINGS/protocol/packets"
+	"M00DSWINGS/protocol/photon"
+	"github.com/google/gopacket/pcap"
+	"testing"
+	"time"
+)
+
+func TestLoggerDecodesResponsesOnly(t *testing.T) {
+	l := NewLogger(pcap.Interface{})
+	l.RegisterResponse(enums.OpTypeChangeCluster, packets.OpClusterChange{})
+
+	got := make([]interface{}, 0)
+	l.RegisterListeners(func(_ time.Time, data interface{}) {
+		got = append(got, data)
+	})
+
+	params := photon.ReliableMessageParamaters{0: "4000", 253: int16(enums.OpTypeChangeCluster)}
+
+	l.handleOperation(time.Now(), false, enums.OpTypeChangeCluster, params)
+	if len(got) != 0 {
+		t.Fatalf("request was decoded into %+v", got[0])
+	}
+
+	l.handleOperation(time.Now(), true, enums.OpTypeChangeCluster, params)
+	if len(got) != 1 {
+		t.Fatalf("%d packets for the response, want 1", len(got))
+	}
+
+	if change, ok := got[0].(*packets.OpClusterChange); !ok || change.Cluster != "4000" {
+		t.Errorf("response decoded into %+v", got[0])
+	}
+}
@@ -0,0 +1,178 @@
+// Package lootchest Follows loot chests and corpses from spawn to despawn, who opened them, what they held and who
+// took what out of them.
+package lootchest
+
+import (
+	"M00DSWINGS/inventory"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+//go:generate stringer -type=Kind
+type Kind int
+
+const (
+	KindChest Kind = iota
+	KindCorpse
+)
+
+// Take Loot leaving the chest, Looter is the character name
+type Take struct {
+	Looter    string
+	Silver    bool
+	ItemIndex int
+	Quantity  int
+	At        time.Time
+}
+
+// Chest Contents is what the chest held the first time the player opened it, the game only sends contents to
+// players who open the chest themselves. Cluster is the zone it spawned in.
+type Chest struct {
+	ID        int
+	Owner     string
+	Kind      Kind
+	Cluster   string
+	Spawned   time.Time
+	Despawned time.Time
+	Updated   time.Time
+	Container uuid.UUID
+	Contents  []inventory.Stack
+	Openers   []string
+	Takes     []Take
+}
+
+// Tracker Chests are looked up by object ID for the world events and by owner name for grabbed loot, which only
+// names the player or mob the loot came from.
+type Tracker struct {
+	mx         *sync.Mutex
+	chests     map[int]*Chest
+	containers map[uuid.UUID]int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		mx:         new(sync.Mutex),
+		chests:     make(map[int]*Chest),
+		containers: make(map[uuid.UUID]int),
+	}
+}
+
+func (t *Tracker) Spawn(id int, owner string, kind Kind, cluster string, at time.Time) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	t.chests[id] = &Chest{ID: id, Owner: owner, Kind: kind, Cluster: cluster, Spawned: at}
+}
+
+// Update Records a state change of the chest the game announced
+func (t *Tracker) Update(id int, at time.Time) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	if chest, ok := t.chests[id]; ok {
+		chest.Updated = at
+	}
+}
+
+// Attach Records the player opening the chest, it is ignored for containers which aren't chests
+func (t *Tracker) Attach(id int, container uuid.UUID, contents []inventory.Stack, opener string) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	chest, ok := t.chests[id]
+	if !ok {
+		return
+	}
+
+	if chest.Contents == nil {
+		chest.Contents = contents
+	}
+
+	chest.Container = container
+	t.containers[container] = id
+	chest.addOpener(opener)
+}
+
+// Detach Records what the player took while the chest was open, from the changes of its container
+func (t *Tracker) Detach(container uuid.UUID, changes []inventory.Change, looter string, at time.Time) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	chest, ok := t.chests[t.containers[container]]
+	if !ok || chest.Container != container {
+		return
+	}
+
+	for _, change := range changes {
+		if change.Delta < 0 {
+			chest.Takes = append(chest.Takes, Take{Looter: looter, ItemIndex: change.ItemIndex, Quantity: -change.Delta, At: at})
+		}
+	}
+}
+
+// Grab Attributes grabbed loot to the newest chest of the owner, false when no chest of the owner is known
+func (t *Tracker) Grab(owner string, take Take) bool {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	var chest *Chest
+	for _, c := range t.chests {
+		if c.Owner == owner && (chest == nil || c.Spawned.After(chest.Spawned)) {
+			chest = c
+		}
+	}
+
+	if chest == nil {
+		return false
+	}
+
+	chest.addOpener(take.Looter)
+	chest.Takes = append(chest.Takes, take)
+
+	return true
+}
+
+// Despawn Stops tracking the chest and returns its full history, ok is false for objects which aren't chests
+func (t *Tracker) Despawn(id int, at time.Time) (Chest, bool) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	chest, ok := t.chests[id]
+	if !ok {
+		return Chest{}, false
+	}
+
+	delete(t.chests, id)
+	delete(t.containers, chest.Container)
+
+	chest.Despawned = at
+
+	return *chest, true
+}
+
+func (t *Tracker) Get(id int) (Chest, bool) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	chest, ok := t.chests[id]
+	if !ok {
+		return Chest{}, false
+	}
+
+	return *chest, true
+}
+
+func (c *Chest) addOpener(name string) {
+	if name == "" {
+		return
+	}
+
+	for _, opener := range c.Openers {
+		if opener == name {
+			return
+		}
+	}
+
+	c.Openers = append(c.Openers, name)
+}
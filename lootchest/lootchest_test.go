@@ -0,0 +1,69 @@
+package lootchest
+
+import (
+	"M00DSWINGS/inventory"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	start := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	container := uuid.New()
+	tracker := NewTracker()
+
+	tracker.Spawn(10, "Bob", KindCorpse, "4000", start)
+	tracker.Spawn(11, "Bob", KindCorpse, "4000", start.Add(time.Minute))
+	tracker.Spawn(12, "@MOB_CHEST", KindChest, "4000", start)
+
+	// Grabbed loot goes to the newest corpse of Bob
+	if !tracker.Grab("Bob", Take{Looter: "Cleo", ItemIndex: 7, Quantity: 2, At: start.Add(2 * time.Minute)}) {
+		t.Fatal("grab was not attributed")
+	}
+
+	if tracker.Grab("Dave", Take{Looter: "Cleo"}) {
+		t.Error("grab from an unknown owner was attributed")
+	}
+
+	contents := []inventory.Stack{{Object: 100, ItemIndex: 7, Quantity: 3}, {Object: 101, ItemIndex: 900, Quantity: 1}}
+	tracker.Attach(11, container, contents, "Anna")
+	tracker.Attach(11, container, nil, "Anna")
+	tracker.Detach(container, []inventory.Change{
+		{ItemIndex: 7, Before: 3, After: 1, Delta: -2},
+		{ItemIndex: 8, Before: 0, After: 1, Delta: 1},
+	}, "Anna", start.Add(3*time.Minute))
+
+	chest, ok := tracker.Despawn(11, start.Add(5*time.Minute))
+	if !ok {
+		t.Fatal("corpse was not tracked")
+	}
+
+	want := Chest{
+		ID:        11,
+		Owner:     "Bob",
+		Kind:      KindCorpse,
+		Cluster:   "4000",
+		Spawned:   start.Add(time.Minute),
+		Despawned: start.Add(5 * time.Minute),
+		Container: container,
+		Contents:  contents,
+		Openers:   []string{"Cleo", "Anna"},
+		Takes: []Take{
+			{Looter: "Cleo", ItemIndex: 7, Quantity: 2, At: start.Add(2 * time.Minute)},
+			{Looter: "Anna", ItemIndex: 7, Quantity: 2, At: start.Add(3 * time.Minute)},
+		},
+	}
+
+	if !reflect.DeepEqual(chest, want) {
+		t.Errorf("chest\n%+v\nwant\n%+v", chest, want)
+	}
+
+	if _, ok := tracker.Despawn(11, start); ok {
+		t.Error("corpse despawned twice")
+	}
+
+	if _, ok := tracker.Get(10); !ok {
+		t.Error("older corpse was lost")
+	}
+}
@@ -0,0 +1,262 @@
+// Package lootsplit Works out who owes whom after a party session, from what each member picked up and how long
+// they were in the party.
+package lootsplit
+
+import (
+	"M00DSWINGS/protocol"
+	"errors"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+var (
+	InvalidSession   = errors.New("Session ends before it starts")
+	InvalidCallerCut = errors.New("Caller cut must be between 0 and 1")
+	UnknownCaller    = errors.New("Caller is not a member of the session")
+	UnknownMode      = errors.New("Unknown split mode")
+)
+
+//go:generate stringer -type=Mode
+type Mode int
+
+const (
+	// ModeEqual Every member who was in the party during the session gets the same share
+	ModeEqual Mode = iota
+	// ModeTimeWeighted Shares follow the time each member spent in the party during the session
+	ModeTimeWeighted
+)
+
+// ParseMode Accepts "equal" and "time"
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "equal":
+		return ModeEqual, nil
+	case "time":
+		return ModeTimeWeighted, nil
+	default:
+		return ModeEqual, UnknownMode
+	}
+}
+
+// Interval A stretch of time a member was in the party, a zero End means they still are
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+type Member struct {
+	ID        uuid.UUID
+	Name      string
+	Intervals []Interval
+}
+
+// Pickup Something a player looted, Looter is the character name as the game reports it. The Quantity of
+// silver is the fixed point amount the game sends.
+type Pickup struct {
+	Looter    string
+	From      string
+	Silver    bool
+	ItemIndex int
+	Quantity  int
+	At        time.Time
+}
+
+type Rules struct {
+	Mode Mode
+	// CallerCut The fraction of the pot the caller gets before the rest is split
+	CallerCut float64
+	Caller    uuid.UUID
+	// ItemValue Prices a single item, items are worth nothing when it is nil
+	ItemValue func(index int) int64
+}
+
+type Session struct {
+	Start   time.Time
+	End     time.Time
+	Members []Member
+	Pickups []Pickup
+}
+
+// Settlement Balance is Share minus Holding, positive means the member is owed silver and negative that they owe it
+type Settlement struct {
+	Member  uuid.UUID
+	Name    string
+	Weight  time.Duration
+	Share   int64
+	Holding int64
+	Balance int64
+}
+
+// Report Ignored counts the value of pickups by players who were not in the party at the time, they are left
+// out of the pot.
+type Report struct {
+	Mode      Mode
+	Total     int64
+	CallerCut int64
+	Ignored   int64
+	Entries   []Settlement
+}
+
+// Split Builds the settlement report of the session. Shares are whole silver, the remainder of the division goes
+// one silver at a time to the members in report order so the shares always add up to the pot.
+func Split(s Session, rules Rules) (*Report, error) {
+	if s.End.Before(s.Start) {
+		return nil, InvalidSession
+	}
+
+	if rules.CallerCut < 0 || rules.CallerCut > 1 {
+		return nil, InvalidCallerCut
+	}
+
+	report := &Report{Mode: rules.Mode, Entries: make([]Settlement, 0, len(s.Members))}
+	byName := make(map[string]int)
+
+	for _, member := range s.Members {
+		weight := member.presence(s.Start, s.End)
+		if weight == 0 && !member.presentAt(s.Start) {
+			continue
+		}
+
+		report.Entries = append(report.Entries, Settlement{Member: member.ID, Name: member.Name, Weight: weight})
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return report.Entries[i].Name < report.Entries[j].Name
+	})
+	for i, entry := range report.Entries {
+		byName[entry.Name] = i
+	}
+
+	members := make(map[string]Member)
+	for _, member := range s.Members {
+		members[member.Name] = member
+	}
+
+	for _, pickup := range s.Pickups {
+		value := pickup.value(rules.ItemValue)
+
+		i, ok := byName[pickup.Looter]
+		if !ok || pickup.At.Before(s.Start) || pickup.At.After(s.End) || !members[pickup.Looter].presentAt(pickup.At) {
+			report.Ignored += value
+			continue
+		}
+
+		report.Entries[i].Holding += value
+		report.Total += value
+	}
+
+	pot := report.Total
+
+	if rules.CallerCut > 0 && pot > 0 {
+		caller := -1
+		for i, entry := range report.Entries {
+			if entry.Member == rules.Caller {
+				caller = i
+			}
+		}
+
+		if caller < 0 {
+			return nil, UnknownCaller
+		}
+
+		report.CallerCut = int64(float64(pot) * rules.CallerCut)
+		report.Entries[caller].Share += report.CallerCut
+		pot -= report.CallerCut
+	}
+
+	report.share(pot, rules.Mode)
+
+	for i := range report.Entries {
+		report.Entries[i].Balance = report.Entries[i].Share - report.Entries[i].Holding
+	}
+
+	return report, nil
+}
+
+func (r *Report) share(pot int64, mode Mode) {
+	weights := make([]int64, len(r.Entries))
+	var total int64
+
+	for i, entry := range r.Entries {
+		weights[i] = 1
+		if mode == ModeTimeWeighted {
+			// Whole seconds keep pot%total*weight well inside an int64
+			weights[i] = int64(entry.Weight / time.Second)
+		}
+
+		total += weights[i]
+	}
+
+	// Everyone was in the party for no time at all, fall back to an equal split
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+
+		total = int64(len(weights))
+	}
+
+	if total == 0 {
+		return
+	}
+
+	var given int64
+	for i := range r.Entries {
+		share := pot/total*weights[i] + pot%total*weights[i]/total
+		r.Entries[i].Share += share
+		given += share
+	}
+
+	for i := 0; given < pot; i = (i + 1) % len(r.Entries) {
+		if weights[i] == 0 {
+			continue
+		}
+
+		r.Entries[i].Share++
+		given++
+	}
+}
+
+func (p Pickup) value(itemValue func(index int) int64) int64 {
+	if p.Silver {
+		return int64(protocol.DecodeFixedPoint(int64(p.Quantity)))
+	}
+
+	if itemValue == nil {
+		return 0
+	}
+
+	return itemValue(p.ItemIndex) * int64(p.Quantity)
+}
+
+// presence Returns how long the member was in the party between start and end
+func (m Member) presence(start time.Time, end time.Time) time.Duration {
+	var total time.Duration
+
+	for _, interval := range m.Intervals {
+		from, to := interval.Start, interval.End
+		if to.IsZero() || to.After(end) {
+			to = end
+		}
+		if from.Before(start) {
+			from = start
+		}
+
+		if to.After(from) {
+			total += to.Sub(from)
+		}
+	}
+
+	return total
+}
+
+func (m Member) presentAt(at time.Time) bool {
+	for _, interval := range m.Intervals {
+		if !at.Before(interval.Start) && (interval.End.IsZero() || !at.After(interval.End)) {
+			return true
+		}
+	}
+
+	return false
+}
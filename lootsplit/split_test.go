@@ -0,0 +1,140 @@
+package lootsplit
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+
+func at(minutes int) time.Time {
+	return start.Add(time.Duration(minutes) * time.Minute)
+}
+
+func member(name string, intervals ...Interval) Member {
+	return Member{ID: uuid.NewSHA1(uuid.Nil, []byte(name)), Name: name, Intervals: intervals}
+}
+
+// silver Makes a pickup of the amount of whole silver, as the fixed point quantity the game sends
+func silver(looter string, minute int, amount int) Pickup {
+	return Pickup{Looter: looter, Silver: true, Quantity: amount * 10000, At: at(minute)}
+}
+
+func TestSplit(t *testing.T) {
+	anna := member("Anna", Interval{Start: at(0)})
+	bob := member("Bob", Interval{Start: at(0), End: at(30)})
+	cleo := member("Cleo", Interval{Start: at(30)}, Interval{Start: at(90), End: at(100)})
+
+	tests := []struct {
+		name    string
+		session Session
+		rules   Rules
+		ignored int64
+		shares  map[string]int64
+	}{
+		{
+			name:    "equal",
+			session: Session{Start: at(0), End: at(60), Members: []Member{anna, bob}, Pickups: []Pickup{silver("Anna", 10, 1000)}},
+			shares:  map[string]int64{"Anna": 500, "Bob": 500},
+		},
+		{
+			name:    "remainder",
+			session: Session{Start: at(0), End: at(60), Members: []Member{anna, bob, cleo}, Pickups: []Pickup{silver("Bob", 10, 100)}},
+			shares:  map[string]int64{"Anna": 34, "Bob": 33, "Cleo": 33},
+		},
+		{
+			name:    "time weighted",
+			session: Session{Start: at(0), End: at(60), Members: []Member{anna, bob, cleo}, Pickups: []Pickup{silver("Anna", 10, 1200)}},
+			rules:   Rules{Mode: ModeTimeWeighted},
+			shares:  map[string]int64{"Anna": 600, "Bob": 300, "Cleo": 300},
+		},
+		{
+			name:    "caller cut",
+			session: Session{Start: at(0), End: at(60), Members: []Member{anna, bob}, Pickups: []Pickup{silver("Bob", 10, 1000)}},
+			rules:   Rules{CallerCut: 0.1, Caller: anna.ID},
+			shares:  map[string]int64{"Anna": 550, "Bob": 450},
+		},
+		{
+			name: "looter outside the party",
+			session: Session{Start: at(0), End: at(60), Members: []Member{anna, bob}, Pickups: []Pickup{
+				silver("Bob", 45, 1000),
+				silver("Dave", 10, 300),
+				silver("Anna", 20, 200),
+			}},
+			ignored: 1300,
+			shares:  map[string]int64{"Anna": 100, "Bob": 100},
+		},
+		{
+			name: "items",
+			session: Session{Start: at(0), End: at(60), Members: []Member{anna, bob}, Pickups: []Pickup{
+				{Looter: "Bob", ItemIndex: 7, Quantity: 3, At: at(5)},
+				{Looter: "Bob", ItemIndex: 8, Quantity: 1, At: at(5)},
+			}},
+			rules: Rules{ItemValue: func(index int) int64 {
+				if index == 7 {
+					return 100
+				}
+				return 0
+			}},
+			shares: map[string]int64{"Anna": 150, "Bob": 150},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Split(tt.session, tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Ignored != tt.ignored {
+				t.Errorf("ignored %d, want %d", report.Ignored, tt.ignored)
+			}
+
+			if len(report.Entries) != len(tt.shares) {
+				t.Fatalf("%d entries, want %d", len(report.Entries), len(tt.shares))
+			}
+
+			var shares, balances int64
+			for _, entry := range report.Entries {
+				if entry.Share != tt.shares[entry.Name] {
+					t.Errorf("%s gets %d, want %d", entry.Name, entry.Share, tt.shares[entry.Name])
+				}
+
+				shares += entry.Share
+				balances += entry.Balance
+			}
+
+			if shares != report.Total || balances != 0 {
+				t.Errorf("shares add up to %d of %d, balances to %d", shares, report.Total, balances)
+			}
+		})
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	anna := member("Anna", Interval{Start: at(0)})
+	session := Session{Start: at(0), End: at(60), Members: []Member{anna}, Pickups: []Pickup{silver("Anna", 10, 100)}}
+
+	if _, err := Split(Session{Start: at(60), End: at(0)}, Rules{}); err != InvalidSession {
+		t.Errorf("reversed session: %v", err)
+	}
+
+	if _, err := Split(session, Rules{CallerCut: 1.5}); err != InvalidCallerCut {
+		t.Errorf("caller cut above 1: %v", err)
+	}
+
+	if _, err := Split(session, Rules{CallerCut: 0.1, Caller: uuid.New()}); err != UnknownCaller {
+		t.Errorf("caller outside the party: %v", err)
+	}
+}
+
+func TestPickupValueOfSilver(t *testing.T) {
+	// 5:1550115 of the EvOtherGrabbedLoot sample in protocol/enums/events.go
+	pickup := Pickup{Looter: "Triky313", Silver: true, Quantity: 1550115}
+
+	if got := pickup.value(nil); got != 155 {
+		t.Errorf("silver pickup worth %d, want 155", got)
+	}
+}
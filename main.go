@@ -1,12 +1,27 @@
 package main
 
 import (
-	"M00DSWINGS/protocol/enums"
+	"M00DSWINGS/audit"
+	"M00DSWINGS/combat"
+	"M00DSWINGS/identity"
+	"M00DSWINGS/inventory"
+	"M00DSWINGS/items"
+	"M00DSWINGS/lootchest"
+	"M00DSWINGS/lootsplit"
+	"M00DSWINGS/meter"
+	"M00DSWINGS/pricing"
 	"M00DSWINGS/protocol/packets"
+	"M00DSWINGS/protocol/photon"
+	"M00DSWINGS/storage"
 	"M00DSWINGS/utils"
+	"context"
 	"flag"
 	"github.com/google/gopacket/pcap"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 var (
@@ -14,22 +29,88 @@ var (
 	interfaceName string
 	serverAddr    string
 	interfaceObj  pcap.Interface
+	readFile      string
+	scenarioPath  string
+	realtime      bool
+	recordDir     string
+	recordSize    int64
+	recordAge     time.Duration
+	protocolName  string
+	databasePath  string
+	splitMode     lootsplit.Mode
+	callerCut     float64
+	itemsPath     string
+	itemsLanguage string
+	pricesPath    string
+	priceCache    string
+	priceAge      time.Duration
+	priceAPI      string
+	auditPolicy   audit.Policy
+	fightsDir     string
 )
 
-func init() {
-	flag.StringVar(&interfaceName, "interface", "", "Network interface to use")
+// parseFlags Reads the command line and resolves the capture device
+func parseFlags() {
+	flag.StringVar(&interfaceName, "interface", "", "Network interface to use, by description or name")
 	flag.StringVar(&serverAddr, "server", "ws://85.192.42.52:3000", "Server address")
+	flag.StringVar(&readFile, "read", "", "Replay packets from a pcap/pcapng file instead of sniffing")
+	flag.StringVar(&scenarioPath, "scenario", "", "Play a simulator scenario straight into the logger instead of sniffing")
+	flag.BoolVar(&realtime, "realtime", false, "Replay packets with their original timing")
+	flag.StringVar(&recordDir, "record", "", "Record sniffed packets into rotating pcapng files in this directory")
+	flag.Int64Var(&recordSize, "record-size", 100, "Rotate recorded segments after this many megabytes")
+	flag.DurationVar(&recordAge, "record-age", time.Hour, "Rotate recorded segments after this duration")
+	flag.StringVar(&protocolName, "protocol", "auto", "Photon protocol to decode: 16, 18 or auto")
+	flag.StringVar(&databasePath, "db", "", "Persist characters, parties and loot into this database file")
+	splitName := flag.String("split", "equal", "Loot split mode: equal or time")
+	flag.StringVar(&itemsPath, "items", "", "Item dump (items.json, items.txt or items.xml) to resolve item indices with")
+	flag.StringVar(&itemsLanguage, "items-lang", items.DefaultLanguage, "Language of the item names in items.json")
+	flag.StringVar(&pricesPath, "prices", "", "CSV file of fixed item prices (item,price), they win over every other source")
+	flag.StringVar(&priceCache, "price-cache", "", "Keep prices learned from the game and the price API in this file")
+	flag.DurationVar(&priceAge, "price-age", 24*time.Hour, "Prices older than this are looked up again")
+	flag.StringVar(&priceAPI, "price-api", "", "Price API base URL in the Albion Online Data format")
+	trustedGuilds := flag.String("trusted-guilds", "", "Comma separated guilds whose members may join without a warning")
+	trustedAlliances := flag.String("trusted-alliances", "", "Comma separated alliances whose members may join without a warning")
+	flag.StringVar(&fightsDir, "fights", "", "Write a JSON report of every fight of the party into this directory")
+	flag.Float64Var(&callerCut, "caller-cut", 0, "Fraction of the loot the party leader gets before splitting, e.g. 0.1")
 
 	flag.Parse()
 
-	if !utils.CheckPcapInstalled() {
-		log.Fatal("pcap is not installed")
+	if splitMode, err = lootsplit.ParseMode(*splitName); err != nil {
+		log.Fatal(err)
 	}
 
+	auditPolicy = audit.Policy{Guilds: audit.ParseList(*trustedGuilds), Alliances: audit.ParseList(*trustedAlliances)}
+
 	if serverAddr == "" {
 		log.Fatal("server address is required")
 	}
 
+	if readFile != "" && scenarioPath != "" {
+		log.Fatal("a capture and a scenario can't be played at the same time")
+	}
+
+	if readFile != "" {
+		if recordDir != "" {
+			log.Fatal("recording is not supported while replaying a capture")
+		}
+
+		log.Printf("Replaying capture %s", readFile)
+		return
+	}
+
+	if scenarioPath != "" {
+		if recordDir != "" {
+			log.Fatal("recording is not supported while playing a scenario")
+		}
+
+		log.Printf("Playing scenario %s", scenarioPath)
+		return
+	}
+
+	if !utils.CheckPcapInstalled() {
+		log.Fatal("pcap is not installed")
+	}
+
 	switch {
 	case interfaceName == "":
 		interfaceObj, err = utils.GetDefaultDevice()
@@ -45,152 +126,167 @@ func init() {
 }
 
 func main() {
+	parseFlags()
+
 	l := NewLogger(interfaceObj)
 
-	l.RegisterOperation(enums.OpTypeJoin, packets.OpJoinGame{})
-
-	// Party events
-	l.RegisterEvent(enums.EventTypePartyPlayerJoined, packets.EvPartySinglePlayerJoined{})
-	l.RegisterEvent(enums.EventTypePartyJoined, packets.EvPartyJoined{})
-	l.RegisterEvent(enums.EventTypePartyPlayerLeft, packets.EvPartyLeft{})
-	l.RegisterEvent(enums.EventTypeNewCharacter, packets.EvNewCharacter{})
-	l.RegisterEvent(enums.EventTypeCharacterStats, packets.EvCharacterStats{})
-	l.RegisterEvent(enums.EventTypePartyLeaderChanged, packets.EvPartyLeaderChanged{})
-	l.RegisterEvent(enums.EventTypePartyDisbanded, packets.EvPartyDisbanded{})
-
-	// Log Party events
-	l.RegisterEvent(enums.EventTypePartyReadyCheckUpdate, packets.EvPartyReadyCheck{})
-	l.RegisterEvent(enums.EventTypePartyPlayerUpdated, packets.Logger{})
-	l.RegisterEvent(enums.EventTypePartyInvitationAnswer, packets.Logger{})
-	l.RegisterEvent(enums.EventTypePartyJoinRequestAnswer, packets.Logger{})
-	l.RegisterEvent(enums.EventTypePartyLootItems, packets.Logger{})
-	l.RegisterEvent(enums.EventTypePartyLootItemsRemoved, packets.Logger{})
-
-	// Loot events
-	l.RegisterOperation(enums.OpTypeInventoryMoveItem, packets.OpInventoryMoveItems{})
-	l.RegisterEvent(enums.EventTypeNewSimpleItem, packets.EvNewSimpleItem{})
-	l.RegisterEvent(enums.EventTypeNewLootChest, packets.EvNewLootChest{})
-	l.RegisterEvent(enums.EventTypeNewLoot, packets.EvNewLoot{})
-	l.RegisterEvent(enums.EventTypeAttachItemContainer, packets.EvAttachItemContainer{})
-	l.RegisterEvent(enums.EventTypeDetachItemContainer, packets.EvDetachItemContainer{})
-	l.RegisterEvent(enums.EventTypeUpdateLootChest, packets.EvUpdateLootChest{})
-	l.RegisterEvent(enums.EventTypeOtherGrabbedLoot, packets.EvOtherGrabbedLoot{})
-	l.RegisterEvent(enums.EventTypeInventoryPutItem, packets.EvInventoryPutItems{})
+	deserializer, err := photon.NewDeserializer(protocolName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	l.RegisterDeserializer(deserializer)
 
-	ws := NewWebSocketClient(serverAddr)
-	defer ws.Close()
+	for _, operation := range packets.Operations {
+		l.RegisterOperation(operation.Type, operation.Packet)
+	}
 
-	l.RegisterListeners(func(data interface{}) {
-		switch d := data.(type) {
-		case *packets.OpJoinGame:
-			log.Printf("Joined game with Character ID: %s, Name: %s, Guild: %s, Alliance: %s", d.CharacterID, d.CharacterName, d.GuildName, d.AllianceName)
-			if err := ws.Initialize(d.CharacterID, d.CharacterName, d.GuildName, d.AllianceName); err != nil {
-				log.Fatal(err)
-			}
+	for _, operation := range packets.Responses {
+		l.RegisterResponse(operation.Type, operation.Packet)
+	}
 
-		case *packets.EvNewCharacter:
-			if err := ws.CreateNewChar(d.PlayerUID, d.PlayerName, d.GuildName, d.AllianceName); err != nil {
-				log.Println(err)
-			}
+	for _, event := range packets.Events {
+		l.RegisterEvent(event.Type, event.Packet)
+	}
 
-		case *packets.EvCharacterStats:
-			if err := ws.UpdateCharacterStats(d.PlayerName, d.GuildName, d.AllianceName); err != nil {
-				log.Println(err)
-			}
+	identities := identity.NewRegistry()
 
-		case *packets.EvPartySinglePlayerJoined:
-			if err := ws.CreateNewChar(d.PlayerUID, d.PlayerName, "", ""); err != nil {
-				log.Println(err)
-			}
+	game := NewGameDataManager(identities)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
 
-			if err := ws.AddPartyPlayer(d.PlayerUID); err != nil {
-				log.Println(err)
-			}
+		if err := game.Close(ctx); err != nil {
+			log.Printf("Failed to close store: %v", err)
+		}
+	}()
 
-		case *packets.EvPartyJoined:
-			for i, playerUsername := range d.PlayerUsernames {
-				if err := ws.CreateNewChar(d.PlayersUuid[i], playerUsername, "", ""); err != nil {
-					log.Println(err)
-				}
-			}
+	if databasePath != "" {
+		store, err := storage.NewBoltStore(databasePath)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-			if err := ws.JoinParty(d.PartyLeader, d.PlayersUuid); err != nil {
-				log.Println(err)
-			}
+		if err := game.RegisterStore(store); err != nil {
+			log.Fatal(err)
+		}
 
-		case *packets.EvPartyLeft:
-			if err := ws.RemovePartyPlayer(d.PlayerUID); err != nil {
-				log.Println(err)
-			}
+		log.Printf("Storing session data in %s", databasePath)
+	}
 
-		case *packets.EvPartyDisbanded:
-			if err := ws.DisbandParty(); err != nil {
-				log.Println(err)
-			}
+	ws := NewWebSocketClient(serverAddr)
+	defer ws.Close()
 
-		case *packets.EvPartyLeaderChanged:
-			if err := ws.UpdatePartyLeader(d.NewPartyLeader); err != nil {
-				log.Println(err)
-			}
+	listener := &Listener{
+		game:       game,
+		ws:         ws,
+		identities: identities,
+		inventory:  inventory.New(),
+		chests:     lootchest.NewTracker(),
+		audit:      audit.NewLog(auditPolicy),
+		meter:      meter.New(time.Time{}),
+		combat: combat.New(30*time.Second, time.Second, func(object int) (combat.Combatant, bool) {
+			char, ok := identities.ByObject(object)
+			return combat.Combatant{ID: char.ID, Name: char.Name}, ok
+		}),
+		clock: newClock(),
+	}
+	defer listener.reportSession()
 
-		case *packets.OpInventoryMoveItems:
-			if err := ws.MoveItems(d.FromSlot, d.FromUUID, d.ToSlot, d.ToUUID); err != nil {
-				log.Println(err)
-			}
+	if itemsPath != "" {
+		db, err := items.Load(itemsPath, itemsLanguage)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-		case *packets.EvInventoryPutItems:
-			if err := ws.PutItems(d.ObjectId, d.ContainerId, d.SlotId); err != nil {
-				log.Println(err)
-			}
+		game.RegisterItems(db)
+		ws.RegisterItems(db)
 
-		case *packets.EvNewLootChest:
-			if err := ws.CreateNewLootChest(d.Id, d.Owner); err != nil {
-				log.Println(err)
-			}
+		log.Printf("Loaded %d items from %s", db.Len(), itemsPath)
+	}
 
-		case *packets.EvNewLoot:
-			if err := ws.CreateNewLoot(d.Id, d.Owner); err != nil {
-				log.Println(err)
-			}
+	market, err := pricing.NewCache(priceCache, priceAge)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := market.Save(); err != nil {
+			log.Println(err)
+		}
+	}()
 
-		case *packets.EvUpdateLootChest:
-			if err := ws.UpdateLootChest(d.Id); err != nil {
-				log.Println(err)
-			}
+	prices := pricing.Chain{market}
 
-		case *packets.EvOtherGrabbedLoot:
-			if err := ws.OtherGrabLoot(d.LootedFromName, d.LooterByName, d.IsSilver, d.ItemIndex, d.Quantity); err != nil {
-				log.Println(err)
-			}
+	if pricesPath != "" {
+		static, err := pricing.LoadCSV(pricesPath)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-		case *packets.EvNewSimpleItem:
-			if err := ws.NewSimpleItem(d.Id, d.ItemIndex, d.Quantity); err != nil {
-				log.Println(err)
-			}
+		prices = append(pricing.Chain{static}, prices...)
+	}
 
-		case *packets.EvAttachItemContainer:
-			if err := ws.AttachItemContainer(d.Id, d.ContainerUUID, d.Items, d.Slots); err != nil {
-				log.Println(err)
-			}
+	if priceAPI != "" {
+		api := pricing.NewHTTPProvider(priceAPI, market)
+		defer api.Close()
 
-		case *packets.EvDetachItemContainer:
-			if err := ws.DetachItemContainer(d.ContainerUUID); err != nil {
-				log.Println(err)
-			}
+		prices = append(prices, api)
+	}
 
-		case *packets.EvPartyReadyCheck:
-			if err := ws.PartyReadCheck(d.Members, d.Status); err != nil {
-				log.Println(err)
-			}
+	game.RegisterPrices(prices, market)
 
-		default:
-			log.Println(data)
-		}
-	})
+	// The server loses its state when the connection drops, send it everything it needs again
+	ws.RegisterConnect(listener.resync)
+
+	l.RegisterListeners(listener.Handle)
+
+	// A fight ends once the party goes quiet, not only when the next one starts
+	l.RegisterIdle(listener.expireFight)
+
+	// Ctrl-C stops the logger instead of killing the process, the store, the recorder and the session report are
+	// only closed by the deferred calls
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Printf("Stopping")
+		l.Stop()
+	}()
 
 	l.RegisterDisconnect(func() {
 		log.Printf("Disconnceted!")
+		listener.reportSession()
 	})
 
+	if readFile != "" {
+		if err := l.ReplayAndServe(readFile, realtime); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if scenarioPath != "" {
+		if err := l.PlayAndServe(scenarioPath); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if recordDir != "" {
+		recorder, err := NewRecorder(recordDir, recordSize*1024*1024, recordAge)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer func() {
+			if err := recorder.Close(); err != nil {
+				log.Printf("Failed to close recorder: %v", err)
+			}
+		}()
+
+		log.Printf("Recording packets to %s", recordDir)
+		l.RegisterRecorder(recorder)
+	}
+
 	l.ListenAndServe()
 }
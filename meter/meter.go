@@ -0,0 +1,173 @@
+// Package meter Measures the fame and silver the player makes in a session, in total and per cluster.
+package meter
+
+import (
+	"sync"
+	"time"
+)
+
+// Totals Silver is what the player picked up, Balance how much their purse changed
+// including what they spent
+type Totals struct {
+	Fame          float64
+	Silver        float64
+	Balance       float64
+	Duration      time.Duration
+	FamePerHour   float64
+	SilverPerHour float64
+}
+
+type ClusterTotals struct {
+	Cluster string
+	Totals
+}
+
+// Summary Clusters are in the order the player first entered them
+type Summary struct {
+	Start    time.Time
+	End      time.Time
+	Session  Totals
+	Clusters []ClusterTotals
+}
+
+type bucket struct {
+	fame     float64
+	silver   float64
+	balance  float64
+	duration time.Duration
+}
+
+// Meter Time counts towards the cluster the player is in, gains before the first cluster go to the "" cluster
+type Meter struct {
+	mx       *sync.Mutex
+	start    time.Time
+	clusters map[string]*bucket
+	order    []string
+	current  string
+	entered  time.Time
+	purse    float64
+	hasPurse bool
+}
+
+// New A zero start leaves the meter to Start, so it begins with the first packet instead of the program
+func New(start time.Time) *Meter {
+	m := &Meter{mx: new(sync.Mutex), start: start, clusters: make(map[string]*bucket), entered: start}
+	m.bucket("")
+
+	return m
+}
+
+// Start Starts a meter created without a start at, later calls are ignored
+func (m *Meter) Start(at time.Time) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if !m.start.IsZero() {
+		return
+	}
+
+	m.start, m.entered = at, at
+}
+
+// Enter Moves the meter to the cluster, the time since the last move goes to the cluster the player left
+func (m *Meter) Enter(cluster string, at time.Time) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if cluster == m.current {
+		return
+	}
+
+	m.leave(at)
+	m.current = cluster
+	m.bucket(cluster)
+}
+
+func (m *Meter) AddFame(fame float64) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.bucket(m.current).fame += fame
+}
+
+func (m *Meter) AddSilver(silver float64) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.bucket(m.current).silver += silver
+}
+
+// SetPurse Records the silver the player holds, the change since the last call goes to the balance
+func (m *Meter) SetPurse(silver float64) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.hasPurse {
+		m.bucket(m.current).balance += silver - m.purse
+	}
+
+	m.purse, m.hasPurse = silver, true
+}
+
+// Summary Returns the totals up to at, the current cluster counts until then
+func (m *Meter) Summary(at time.Time) Summary {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	summary := Summary{Start: m.start, End: at, Clusters: make([]ClusterTotals, 0, len(m.order))}
+
+	var session bucket
+	for _, cluster := range m.order {
+		b := *m.clusters[cluster]
+		if cluster == m.current && at.After(m.entered) {
+			b.duration += at.Sub(m.entered)
+		}
+
+		session.fame += b.fame
+		session.silver += b.silver
+		session.balance += b.balance
+		session.duration += b.duration
+
+		if cluster == "" && b == (bucket{}) {
+			continue
+		}
+
+		summary.Clusters = append(summary.Clusters, ClusterTotals{Cluster: cluster, Totals: b.totals()})
+	}
+
+	summary.Session = session.totals()
+
+	return summary
+}
+
+// leave Adds the time in the current cluster, mx must be held
+func (m *Meter) leave(at time.Time) {
+	if at.After(m.entered) {
+		m.bucket(m.current).duration += at.Sub(m.entered)
+	}
+
+	m.entered = at
+}
+
+// bucket Returns the bucket of the cluster, creating it on first use. mx must be held.
+func (m *Meter) bucket(cluster string) *bucket {
+	b, ok := m.clusters[cluster]
+	if !ok {
+		b = new(bucket)
+		m.clusters[cluster] = b
+		m.order = append(m.order, cluster)
+	}
+
+	return b
+}
+
+func (b bucket) totals() Totals {
+	t := Totals{Fame: b.fame, Silver: b.silver, Balance: b.balance, Duration: b.duration}
+
+	if hours := b.duration.Hours(); hours > 0 {
+		t.FamePerHour = b.fame / hours
+		t.SilverPerHour = b.silver / hours
+	}
+
+	return t
+}
@@ -0,0 +1,42 @@
+package meter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeter(t *testing.T) {
+	start := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	m := New(start)
+
+	m.SetPurse(1000)
+	m.Enter("4000", start)
+	m.AddFame(60000)
+	m.AddSilver(3000)
+	m.SetPurse(4000)
+
+	m.Enter("3004", start.Add(30*time.Minute))
+	m.AddFame(10000)
+	m.SetPurse(2500)
+
+	summary := m.Summary(start.Add(time.Hour))
+
+	if len(summary.Clusters) != 2 {
+		t.Fatalf("clusters %+v", summary.Clusters)
+	}
+
+	first := summary.Clusters[0]
+	if first.Cluster != "4000" || first.Duration != 30*time.Minute || first.FamePerHour != 120000 || first.SilverPerHour != 6000 || first.Balance != 3000 {
+		t.Errorf("first cluster %+v", first)
+	}
+
+	second := summary.Clusters[1]
+	if second.Cluster != "3004" || second.Duration != 30*time.Minute || second.Balance != -1500 {
+		t.Errorf("second cluster %+v", second)
+	}
+
+	session := summary.Session
+	if session.Fame != 70000 || session.Silver != 3000 || session.Duration != time.Hour || session.FamePerHour != 70000 || session.Balance != 1500 {
+		t.Errorf("session %+v", session)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"M00DSWINGS/lootsplit"
 	"M00DSWINGS/utils"
 	"github.com/google/uuid"
 	"time"
@@ -13,6 +14,18 @@ const (
 	PartyActionJoin PartyAction = iota
 	PartyActionLeave
 	PartySelfLeave
+	PartyActionKick
+	PartyActionLeaderChange
+	PartyActionDisband
+	PartyActionReform
+)
+
+//go:generate stringer -type=PartyState
+type PartyState int
+
+const (
+	PartyStateActive PartyState = iota
+	PartyStateDisbanded
 )
 
 type PartyHistoryEntry struct {
@@ -21,72 +34,262 @@ type PartyHistoryEntry struct {
 	User      uuid.UUID
 }
 
+// Party Tracks the members of a party. Every change goes through the methods below, which keep
+// Members and History in sync. A disbanded party ignores everything except Reform and SetMembers,
+// which re-forms it. Roles and LootSettings only hold members.
 type Party struct {
-	PartyOwner uuid.UUID
-	Members    *utils.HashSet[uuid.UUID]
-	History    []PartyHistoryEntry
+	ID           uuid.UUID
+	PartyOwner   uuid.UUID
+	Members      *utils.HashSet[uuid.UUID]
+	History      []PartyHistoryEntry
+	Pickups      []lootsplit.Pickup
+	State        PartyState
+	Roles        map[uuid.UUID]int
+	LootSettings map[uuid.UUID]int
 }
 
-func NewPartyHistory(id uuid.UUID, action PartyAction) PartyHistoryEntry {
+// NewPartyHistory at is when the packet with the change was captured, pickups are timed the same way
+func NewPartyHistory(id uuid.UUID, action PartyAction, at time.Time) PartyHistoryEntry {
 	return PartyHistoryEntry{
 		Action:    action,
-		Timestamp: time.Now().UTC(),
+		Timestamp: at,
 		User:      id,
 	}
 }
 
-func NewParty(partyOwner uuid.UUID, members []uuid.UUID) *Party {
-	set := utils.NewHashSet[uuid.UUID](members...)
+func NewParty(partyOwner uuid.UUID, members []uuid.UUID, at time.Time) *Party {
+	party := &Party{
+		ID:           uuid.New(),
+		PartyOwner:   partyOwner,
+		Members:      utils.NewHashSet[uuid.UUID](),
+		History:      make([]PartyHistoryEntry, 0),
+		Pickups:      make([]lootsplit.Pickup, 0),
+		State:        PartyStateActive,
+		Roles:        make(map[uuid.UUID]int),
+		LootSettings: make(map[uuid.UUID]int),
+	}
 
-	return &Party{
-		PartyOwner: partyOwner,
-		Members:    set,
-		History:    make([]PartyHistoryEntry, 0),
+	for _, id := range members {
+		party.AddPlayer(id, at)
 	}
+
+	return party
+}
+
+func (p *Party) IsActive() bool {
+	return p.State == PartyStateActive
 }
 
-func (p *Party) AddPlayer(userId uuid.UUID) {
-	if p.Members.Contains(userId) {
+func (p *Party) AddPlayer(userId uuid.UUID, at time.Time) {
+	if !p.IsActive() || p.Members.Contains(userId) {
 		return
 	}
 
-	p.addHistory(userId, PartyActionJoin)
+	p.addHistory(userId, PartyActionJoin, at)
 	p.Members.Add(userId)
 }
 
-func (p *Party) RemoveSelf(userId uuid.UUID) {
-	if !p.Members.Contains(userId) {
+// RemoveSelf Records the user leaving the party on their own
+func (p *Party) RemoveSelf(userId uuid.UUID, at time.Time) {
+	p.removeMember(userId, PartySelfLeave, at)
+}
+
+// RemovePlayer Records the user leaving the party
+func (p *Party) RemovePlayer(userId uuid.UUID, at time.Time) {
+	p.removeMember(userId, PartyActionLeave, at)
+}
+
+// ChangeLeader Hands the party over to another member, unknown members join the party first
+func (p *Party) ChangeLeader(userId uuid.UUID, at time.Time) {
+	if !p.IsActive() || p.PartyOwner == userId {
 		return
 	}
 
-	p.addHistory(userId, PartySelfLeave)
-	p.Members.Add(userId)
+	p.AddPlayer(userId, at)
+	p.addHistory(userId, PartyActionLeaderChange, at)
+	p.PartyOwner = userId
 }
 
-func (p *Party) RemovePlayer(userId uuid.UUID) {
-	if !p.Members.Contains(userId) {
+// Disband Marks the party as disbanded and removes every member, the entry is recorded for the leader
+func (p *Party) Disband(at time.Time) {
+	if !p.IsActive() {
 		return
 	}
 
-	p.addHistory(userId, PartyActionLeave)
-	p.Members.Add(userId)
+	p.addHistory(p.PartyOwner, PartyActionDisband, at)
+	p.Members.Clear()
+	p.State = PartyStateDisbanded
+	p.Roles = make(map[uuid.UUID]int)
+	p.LootSettings = make(map[uuid.UUID]int)
+}
+
+// Reform Brings a disbanded party back with a new leader and member list. A party under another leader
+// is a different party, so it gets a new ID and the pickups of the old one are left behind.
+func (p *Party) Reform(leader uuid.UUID, members []uuid.UUID, at time.Time) {
+	if p.IsActive() && p.PartyOwner == leader {
+		p.SetMembers(members, at)
+		return
+	}
+
+	p.Disband(at)
+
+	if p.PartyOwner != leader {
+		p.ID = uuid.New()
+	}
+
+	p.State = PartyStateActive
+	p.PartyOwner = leader
+	p.Pickups = make([]lootsplit.Pickup, 0)
+	p.addHistory(leader, PartyActionReform, at)
+
+	for _, id := range members {
+		p.AddPlayer(id, at)
+	}
 }
 
-func (p *Party) SetMembers(members []uuid.UUID) (removedPlayers []uuid.UUID, addedPlayers []uuid.UUID) {
+func (p *Party) SetMembers(members []uuid.UUID, at time.Time) (removedPlayers []uuid.UUID, addedPlayers []uuid.UUID) {
+	if !p.IsActive() {
+		p.Reform(p.PartyOwner, nil, at)
+	}
+
 	removedPlayers = p.determineRemovedPlayers(members)
 	addedPlayers = p.determineAddedPlayers(members)
 
 	for _, id := range removedPlayers {
-		p.RemovePlayer(id)
+		p.RemovePlayer(id, at)
 	}
 
 	for _, id := range addedPlayers {
-		p.AddPlayer(id)
+		p.AddPlayer(id, at)
 	}
 
 	return removedPlayers, addedPlayers
 }
 
+// MembersAt Returns the members of the party at the given time, replayed from History
+func (p *Party) MembersAt(at time.Time) []uuid.UUID {
+	members := make([]uuid.UUID, 0)
+
+	for _, entry := range p.History {
+		if entry.Timestamp.After(at) {
+			break
+		}
+
+		switch entry.Action {
+		case PartyActionJoin:
+			if !p.contains(entry.User, members) {
+				members = append(members, entry.User)
+			}
+		case PartyActionLeave, PartySelfLeave, PartyActionKick:
+			members = p.without(entry.User, members)
+		case PartyActionDisband:
+			members = members[:0]
+		}
+	}
+
+	return members
+}
+
+// SetRoleFlag Sets the role flag of a member
+func (p *Party) SetRoleFlag(userId uuid.UUID, flag int) bool {
+	if !p.IsActive() || !p.Members.Contains(userId) {
+		return false
+	}
+
+	p.Roles[userId] = flag
+
+	return true
+}
+
+// SetLootSetting Sets the loot setting of a member
+func (p *Party) SetLootSetting(userId uuid.UUID, setting int) bool {
+	if !p.IsActive() || !p.Members.Contains(userId) {
+		return false
+	}
+
+	p.LootSettings[userId] = setting
+
+	return true
+}
+
+// AddPickup Records loot picked up while the party is active, it is what Session splits
+func (p *Party) AddPickup(pickup lootsplit.Pickup) {
+	if !p.IsActive() {
+		return
+	}
+
+	p.Pickups = append(p.Pickups, pickup)
+}
+
+// Session Builds the loot split session of the party, from its last (re)formation until end. History is turned
+// into membership intervals and names resolves the member names the pickups refer to.
+func (p *Party) Session(end time.Time, names func(id uuid.UUID) string) lootsplit.Session {
+	start := 0
+	for i, entry := range p.History {
+		if entry.Action == PartyActionReform {
+			start = i
+		}
+	}
+
+	session := lootsplit.Session{End: end, Pickups: p.Pickups}
+	if len(p.History) > 0 {
+		session.Start = p.History[start].Timestamp
+	}
+
+	members := make(map[uuid.UUID]*lootsplit.Member)
+	order := make([]uuid.UUID, 0)
+
+	closeInterval := func(id uuid.UUID, at time.Time) {
+		member, ok := members[id]
+		if !ok || len(member.Intervals) == 0 {
+			return
+		}
+
+		if last := &member.Intervals[len(member.Intervals)-1]; last.End.IsZero() {
+			last.End = at
+		}
+	}
+
+	for _, entry := range p.History[start:] {
+		switch entry.Action {
+		case PartyActionJoin:
+			member, ok := members[entry.User]
+			if !ok {
+				member = &lootsplit.Member{ID: entry.User, Name: names(entry.User)}
+				members[entry.User] = member
+				order = append(order, entry.User)
+			}
+
+			if n := len(member.Intervals); n == 0 || !member.Intervals[n-1].End.IsZero() {
+				member.Intervals = append(member.Intervals, lootsplit.Interval{Start: entry.Timestamp})
+			}
+		case PartyActionLeave, PartySelfLeave, PartyActionKick:
+			closeInterval(entry.User, entry.Timestamp)
+		case PartyActionDisband:
+			for id := range members {
+				closeInterval(id, entry.Timestamp)
+			}
+		}
+	}
+
+	for _, id := range order {
+		session.Members = append(session.Members, *members[id])
+	}
+
+	return session
+}
+
+func (p *Party) removeMember(userId uuid.UUID, action PartyAction, at time.Time) {
+	if !p.IsActive() || !p.Members.Contains(userId) {
+		return
+	}
+
+	p.addHistory(userId, action, at)
+	p.Members.Remove(userId)
+	delete(p.Roles, userId)
+	delete(p.LootSettings, userId)
+}
+
 func (p *Party) determineRemovedPlayers(members []uuid.UUID) []uuid.UUID {
 	removedPlayers := make([]uuid.UUID, 0)
 
@@ -103,7 +306,7 @@ func (p *Party) determineAddedPlayers(members []uuid.UUID) []uuid.UUID {
 	addedPlayers := make([]uuid.UUID, 0)
 
 	for _, member := range members {
-		if !p.contains(member, p.Members.Values()) {
+		if !p.Members.Contains(member) && !p.contains(member, addedPlayers) {
 			addedPlayers = append(addedPlayers, member)
 		}
 	}
@@ -121,6 +324,18 @@ func (p *Party) contains(id uuid.UUID, slice []uuid.UUID) bool {
 	return false
 }
 
-func (p *Party) addHistory(id uuid.UUID, partyAction PartyAction) {
-	p.History = append(p.History, NewPartyHistory(id, partyAction))
+func (p *Party) without(id uuid.UUID, slice []uuid.UUID) []uuid.UUID {
+	result := slice[:0]
+
+	for _, u := range slice {
+		if u != id {
+			result = append(result, u)
+		}
+	}
+
+	return result
+}
+
+func (p *Party) addHistory(id uuid.UUID, partyAction PartyAction, at time.Time) {
+	p.History = append(p.History, NewPartyHistory(id, partyAction, at))
 }
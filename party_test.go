@@ -0,0 +1,279 @@
+package main
+
+import (
+	"M00DSWINGS/lootsplit"
+	"github.com/google/uuid"
+	"sort"
+	"testing"
+	"time"
+)
+
+// partyStart Is when the party changes of the tests happen unless a test times them itself
+var partyStart = time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+
+func sortedIDs(ids []uuid.UUID) []uuid.UUID {
+	sorted := append([]uuid.UUID(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+
+	return sorted
+}
+
+func equalIDs(a []uuid.UUID, b []uuid.UUID) bool {
+	a, b = sortedIDs(a), sortedIDs(b)
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func historyActions(p *Party) []PartyAction {
+	actions := make([]PartyAction, len(p.History))
+	for i, entry := range p.History {
+		actions[i] = entry.Action
+	}
+
+	return actions
+}
+
+func TestPartyRemovesMembers(t *testing.T) {
+	leader, member := uuid.New(), uuid.New()
+	party := NewParty(leader, []uuid.UUID{leader, member}, partyStart)
+
+	party.RemovePlayer(member, partyStart)
+	if party.Members.Contains(member) {
+		t.Error("RemovePlayer kept the member")
+	}
+
+	party.RemoveSelf(leader, partyStart)
+	if party.Members.Contains(leader) {
+		t.Error("RemoveSelf kept the member")
+	}
+
+	want := []PartyAction{PartyActionJoin, PartyActionJoin, PartyActionLeave, PartySelfLeave}
+	if got := historyActions(party); !equalActions(got, want) {
+		t.Errorf("history %v, want %v", got, want)
+	}
+}
+
+func TestPartySetMembers(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name        string
+		initial     []uuid.UUID
+		members     []uuid.UUID
+		wantRemoved []uuid.UUID
+		wantAdded   []uuid.UUID
+	}{
+		{"unchanged", []uuid.UUID{a, b}, []uuid.UUID{b, a}, nil, nil},
+		{"join", []uuid.UUID{a}, []uuid.UUID{a, b, c}, nil, []uuid.UUID{b, c}},
+		{"leave", []uuid.UUID{a, b, c}, []uuid.UUID{a}, []uuid.UUID{b, c}, nil},
+		{"swap", []uuid.UUID{a, b}, []uuid.UUID{a, c, d}, []uuid.UUID{b}, []uuid.UUID{c, d}},
+		{"duplicates", []uuid.UUID{a}, []uuid.UUID{a, b, b}, nil, []uuid.UUID{b}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			party := NewParty(a, tt.initial, partyStart)
+			history := len(party.History)
+
+			removed, added := party.SetMembers(tt.members, partyStart)
+
+			if !equalIDs(removed, tt.wantRemoved) {
+				t.Errorf("removed %v, want %v", removed, tt.wantRemoved)
+			}
+
+			if !equalIDs(added, tt.wantAdded) {
+				t.Errorf("added %v, want %v", added, tt.wantAdded)
+			}
+
+			if !equalIDs(party.Members.Values(), uniqueIDs(tt.members)) {
+				t.Errorf("members %v, want %v", party.Members.Values(), uniqueIDs(tt.members))
+			}
+
+			if got := len(party.History) - history; got != len(removed)+len(added) {
+				t.Errorf("%d history entries for %d changes", got, len(removed)+len(added))
+			}
+		})
+	}
+}
+
+func TestPartyLifecycle(t *testing.T) {
+	leader, member, newcomer := uuid.New(), uuid.New(), uuid.New()
+	party := NewParty(leader, []uuid.UUID{leader, member}, partyStart)
+
+	party.ChangeLeader(member, partyStart)
+	if party.PartyOwner != member {
+		t.Errorf("leader %v, want %v", party.PartyOwner, member)
+	}
+
+	party.RemovePlayer(leader, partyStart)
+	party.Disband(partyStart)
+
+	if party.IsActive() || len(party.Members.Values()) != 0 {
+		t.Fatalf("disbanded party is %v with %d members", party.State, len(party.Members.Values()))
+	}
+
+	// Nothing happens to a disbanded party until it is re-formed
+	party.AddPlayer(newcomer, partyStart)
+	party.ChangeLeader(newcomer, partyStart)
+	if party.Members.Contains(newcomer) || party.PartyOwner != member {
+		t.Error("disbanded party was changed")
+	}
+
+	party.Reform(newcomer, []uuid.UUID{newcomer, member}, partyStart)
+	if !party.IsActive() || party.PartyOwner != newcomer || !equalIDs(party.Members.Values(), []uuid.UUID{newcomer, member}) {
+		t.Errorf("re-formed party is %v, led by %v with %v", party.State, party.PartyOwner, party.Members.Values())
+	}
+
+	want := []PartyAction{
+		PartyActionJoin, PartyActionJoin,
+		PartyActionLeaderChange, PartyActionLeave, PartyActionDisband,
+		PartyActionReform, PartyActionJoin, PartyActionJoin,
+	}
+	if got := historyActions(party); !equalActions(got, want) {
+		t.Errorf("history %v, want %v", got, want)
+	}
+}
+
+func TestPartyMembersAt(t *testing.T) {
+	leader, member := uuid.New(), uuid.New()
+	// Spread the changes out so each point in time is unambiguous
+	start := partyStart
+	party := NewParty(leader, []uuid.UUID{leader}, start)
+
+	party.AddPlayer(member, start.Add(time.Minute))
+	party.RemovePlayer(member, start.Add(2*time.Minute))
+
+	if got := party.MembersAt(start.Add(90 * time.Second)); !equalIDs(got, []uuid.UUID{leader, member}) {
+		t.Errorf("members %v while both were in the party", got)
+	}
+
+	if got := party.MembersAt(start.Add(3 * time.Minute)); !equalIDs(got, []uuid.UUID{leader}) {
+		t.Errorf("members %v after the member left", got)
+	}
+}
+
+func TestPartySession(t *testing.T) {
+	leader, member := uuid.New(), uuid.New()
+	start := partyStart
+	party := NewParty(leader, []uuid.UUID{leader}, start)
+
+	party.AddPlayer(member, start.Add(10*time.Minute))
+	party.RemovePlayer(member, start.Add(20*time.Minute))
+	party.AddPlayer(member, start.Add(30*time.Minute))
+
+	session := party.Session(start.Add(time.Hour), func(id uuid.UUID) string {
+		return id.String()
+	})
+
+	if !session.Start.Equal(start) || len(session.Members) != 2 {
+		t.Fatalf("session from %v with %d members", session.Start, len(session.Members))
+	}
+
+	intervals := session.Members[1].Intervals
+	if len(intervals) != 2 || !intervals[0].End.Equal(start.Add(20*time.Minute)) || !intervals[1].End.IsZero() {
+		t.Errorf("member intervals %+v", intervals)
+	}
+}
+
+func TestPartyReformKeepsOldSession(t *testing.T) {
+	leader := uuid.New()
+	party := NewParty(leader, []uuid.UUID{leader}, partyStart)
+
+	party.AddPickup(lootsplit.Pickup{Looter: "Anna", ItemIndex: 1, Quantity: 1})
+	party.AddPickup(lootsplit.Pickup{Looter: "Anna", ItemIndex: 2, Quantity: 1})
+
+	old := party.Session(partyStart, func(id uuid.UUID) string {
+		return id.String()
+	})
+
+	party.Disband(partyStart)
+	party.Reform(leader, []uuid.UUID{leader}, partyStart)
+	party.AddPickup(lootsplit.Pickup{Looter: "Bob", ItemIndex: 3, Quantity: 1})
+
+	if len(old.Pickups) != 2 || old.Pickups[0].Looter != "Anna" || old.Pickups[0].ItemIndex != 1 {
+		t.Errorf("pickups of the old session changed to %+v", old.Pickups)
+	}
+
+	if len(party.Pickups) != 1 || party.Pickups[0].Looter != "Bob" {
+		t.Errorf("pickups after the reform %+v", party.Pickups)
+	}
+}
+
+func TestPartyReformUnderNewLeader(t *testing.T) {
+	leader, member := uuid.New(), uuid.New()
+	party := NewParty(leader, []uuid.UUID{leader, member}, partyStart)
+	id := party.ID
+
+	party.AddPickup(lootsplit.Pickup{Looter: "Anna", ItemIndex: 1, Quantity: 1})
+	party.Reform(member, []uuid.UUID{member}, partyStart)
+
+	if party.ID == id || len(party.Pickups) != 0 {
+		t.Errorf("party under a new leader kept ID %v and %d pickups", party.ID, len(party.Pickups))
+	}
+
+	if !party.IsActive() || party.PartyOwner != member || !equalIDs(party.Members.Values(), []uuid.UUID{member}) {
+		t.Errorf("re-formed party is %v, led by %v with %v", party.State, party.PartyOwner, party.Members.Values())
+	}
+
+	want := []PartyAction{
+		PartyActionJoin, PartyActionJoin, PartyActionDisband,
+		PartyActionReform, PartyActionJoin,
+	}
+	if got := historyActions(party); !equalActions(got, want) {
+		t.Errorf("history %v, want %v", got, want)
+	}
+}
+
+func TestPartyRoles(t *testing.T) {
+	leader, member, stranger := uuid.New(), uuid.New(), uuid.New()
+	party := NewParty(leader, []uuid.UUID{leader, member}, partyStart)
+
+	if !party.SetRoleFlag(member, 8) || party.SetRoleFlag(stranger, 8) {
+		t.Error("role flags are only set for members")
+	}
+
+	party.SetLootSetting(member, 1)
+	party.RemovePlayer(member, partyStart)
+
+	if _, ok := party.Roles[member]; ok || len(party.LootSettings) != 0 {
+		t.Errorf("member left behind roles %v, loot settings %v", party.Roles, party.LootSettings)
+	}
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+func equalActions(a []PartyAction, b []PartyAction) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -11,15 +11,20 @@ func _() {
 	_ = x[PartyActionJoin-0]
 	_ = x[PartyActionLeave-1]
 	_ = x[PartySelfLeave-2]
+	_ = x[PartyActionKick-3]
+	_ = x[PartyActionLeaderChange-4]
+	_ = x[PartyActionDisband-5]
+	_ = x[PartyActionReform-6]
 }
 
-const _PartyAction_name = "PartyActionJoinPartyActionLeavePartySelfLeave"
+const _PartyAction_name = "PartyActionJoinPartyActionLeavePartySelfLeavePartyActionKickPartyActionLeaderChangePartyActionDisbandPartyActionReform"
 
-var _PartyAction_index = [...]uint8{0, 15, 31, 45}
+var _PartyAction_index = [...]uint8{0, 15, 31, 45, 60, 83, 101, 118}
 
 func (i PartyAction) String() string {
-	if i < 0 || i >= PartyAction(len(_PartyAction_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_PartyAction_index)-1 {
 		return "PartyAction(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _PartyAction_name[_PartyAction_index[i]:_PartyAction_index[i+1]]
+	return _PartyAction_name[_PartyAction_index[idx]:_PartyAction_index[idx+1]]
 }
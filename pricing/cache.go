@@ -0,0 +1,94 @@
+package pricing
+
+import (
+	"M00DSWINGS/items"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Cache Prices learned while running, from the game itself or a price API. Entries older than maxAge are treated
+// as missing and the cache is kept in a JSON file when it has a path.
+type Cache struct {
+	path   string
+	maxAge time.Duration
+	mx     *sync.RWMutex
+	prices map[string]Price
+}
+
+// NewCache Loads the cache file at path if it exists, an empty path keeps the cache in memory only and a zero
+// maxAge never expires entries
+func NewCache(path string, maxAge time.Duration) (*Cache, error) {
+	c := &Cache{
+		path:   path,
+		maxAge: maxAge,
+		mx:     new(sync.RWMutex),
+		prices: make(map[string]Price),
+	}
+
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &c.prices); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Cache) Price(item items.Item) (Price, bool) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+
+	price, ok := c.prices[Key(item)]
+	if !ok || (c.maxAge > 0 && time.Since(price.Updated) > c.maxAge) {
+		return Price{}, false
+	}
+
+	return price, true
+}
+
+// Set Stores the price, a zero Updated is set to now
+func (c *Cache) Set(item items.Item, price Price) {
+	if price.Updated.IsZero() {
+		price.Updated = time.Now().UTC()
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.prices[Key(item)] = price
+}
+
+// Save Writes the cache file, the file is replaced in one go so a crash never leaves half a cache behind
+func (c *Cache) Save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	c.mx.RLock()
+	data, err := json.MarshalIndent(c.prices, "", "  ")
+	c.mx.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.path)
+}
@@ -0,0 +1,79 @@
+package pricing
+
+import (
+	"M00DSWINGS/items"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// StaticProvider Fixed prices, meant for overriding the other sources
+type StaticProvider struct {
+	byName  map[string]int64
+	byIndex map[int]int64
+}
+
+// LoadCSV Reads "item,price" rows, item being a unique name like T4_BAG@1 or a numeric item index. A header row
+// and # comments are skipped.
+func LoadCSV(path string) (*StaticProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadCSV(file)
+}
+
+func ReadCSV(r io.Reader) (*StaticProvider, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	p := &StaticProvider{byName: make(map[string]int64), byIndex: make(map[int]int64)}
+
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return p, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		item, value := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+
+		price, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			if first {
+				continue
+			}
+
+			line, _ := reader.FieldPos(1)
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if index, err := strconv.Atoi(item); err == nil {
+			p.byIndex[index] = price
+		} else {
+			p.byName[item] = price
+		}
+	}
+}
+
+func (p *StaticProvider) Price(item items.Item) (Price, bool) {
+	value, ok := p.byName[item.UniqueName]
+	if !ok || item.UniqueName == "" {
+		value, ok = p.byIndex[item.Index]
+	}
+
+	if !ok {
+		return Price{}, false
+	}
+
+	return Price{Value: value, Source: "static"}, true
+}
@@ -0,0 +1,137 @@
+package pricing
+
+import (
+	"M00DSWINGS/items"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPProvider Looks prices up in a price API speaking the Albion Online Data format, GET <base>/<UNIQUE_NAME>.json
+// returning [{"item_id": ..., "sell_price_min": ...}, ...]. Lookups run in the background: the first Price of an
+// item queues it and returns nothing, the answer lands in the cache for the next one.
+type HTTPProvider struct {
+	base   string
+	client *http.Client
+	cache  *Cache
+
+	mx      *sync.Mutex
+	pending map[string]bool
+	queue   chan items.Item
+	done    chan struct{}
+}
+
+type apiPrice struct {
+	ItemID       string `json:"item_id"`
+	SellPriceMin int64  `json:"sell_price_min"`
+}
+
+// NewHTTPProvider Starts the lookup worker, results are stored in cache
+func NewHTTPProvider(base string, cache *Cache) *HTTPProvider {
+	p := &HTTPProvider{
+		base:    strings.TrimRight(base, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		cache:   cache,
+		mx:      new(sync.Mutex),
+		pending: make(map[string]bool),
+		queue:   make(chan items.Item, 256),
+		done:    make(chan struct{}),
+	}
+
+	go p.worker()
+
+	return p
+}
+
+func (p *HTTPProvider) Price(item items.Item) (Price, bool) {
+	if price, ok := p.cache.Price(item); ok {
+		return price, true
+	}
+
+	// The API only knows unique names
+	if item.UniqueName == "" {
+		return Price{}, false
+	}
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if p.pending[item.UniqueName] {
+		return Price{}, false
+	}
+
+	select {
+	case p.queue <- item:
+		p.pending[item.UniqueName] = true
+	default:
+		// Queue is full, the item is asked for again on its next pickup
+	}
+
+	return Price{}, false
+}
+
+// Close Stops the worker, queued lookups are dropped
+func (p *HTTPProvider) Close() {
+	close(p.done)
+}
+
+func (p *HTTPProvider) worker() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case item := <-p.queue:
+			price, err := p.fetch(item.UniqueName)
+			if err != nil {
+				log.Printf("Failed to fetch price of %s: %v", item.UniqueName, err)
+
+				// Failed lookups may be retried, items the API has no price for stay pending so they aren't
+				// asked for again
+				p.mx.Lock()
+				delete(p.pending, item.UniqueName)
+				p.mx.Unlock()
+				continue
+			}
+
+			if price.Value > 0 {
+				p.cache.Set(item, price)
+
+				p.mx.Lock()
+				delete(p.pending, item.UniqueName)
+				p.mx.Unlock()
+			}
+		}
+	}
+}
+
+// fetch Returns the lowest sell order over every market the API reports
+func (p *HTTPProvider) fetch(uniqueName string) (Price, error) {
+	resp, err := p.client.Get(p.base + "/" + url.PathEscape(uniqueName) + ".json")
+	if err != nil {
+		return Price{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Price{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var prices []apiPrice
+	if err := json.NewDecoder(resp.Body).Decode(&prices); err != nil {
+		return Price{}, err
+	}
+
+	price := Price{Source: "api", Updated: time.Now().UTC()}
+	for _, entry := range prices {
+		if entry.SellPriceMin > 0 && (price.Value == 0 || entry.SellPriceMin < price.Value) {
+			price.Value = entry.SellPriceMin
+		}
+	}
+
+	return price, nil
+}
@@ -0,0 +1,47 @@
+// Package pricing Estimates the silver value of items from pluggable price sources.
+package pricing
+
+import (
+	"M00DSWINGS/items"
+	"strconv"
+	"time"
+)
+
+// Price The value of a single item in silver
+type Price struct {
+	Value   int64     `json:"value"`
+	Source  string    `json:"source"`
+	Updated time.Time `json:"updated"`
+}
+
+// Provider A price source, ok is false when it has no price for the item. Price is called from the packet loop
+// so providers must not block on I/O.
+type Provider interface {
+	Price(item items.Item) (Price, bool)
+}
+
+// Chain Asks every provider in order, the first one with a price wins
+type Chain []Provider
+
+func (c Chain) Price(item items.Item) (Price, bool) {
+	for _, provider := range c {
+		if provider == nil {
+			continue
+		}
+
+		if price, ok := provider.Price(item); ok {
+			return price, true
+		}
+	}
+
+	return Price{}, false
+}
+
+// Key Identifies the item in price tables, the unique name when the item dump knows it and #index otherwise
+func Key(item items.Item) string {
+	if item.UniqueName != "" {
+		return item.UniqueName
+	}
+
+	return "#" + strconv.Itoa(item.Index)
+}
@@ -0,0 +1,141 @@
+package pricing
+
+import (
+	"M00DSWINGS/items"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var bag = items.Item{Index: 1204, UniqueName: "T4_BAG", Tier: 4}
+
+func TestReadCSV(t *testing.T) {
+	static, err := ReadCSV(strings.NewReader("item,price\n# overrides\nT4_BAG, 2500\n1300,40\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		item items.Item
+		want int64
+		ok   bool
+	}{
+		{bag, 2500, true},
+		{items.Item{Index: 1300}, 40, true},
+		{items.Item{Index: 1300, UniqueName: "T5_BAG"}, 40, true},
+		{items.Item{Index: 1}, 0, false},
+	}
+
+	for _, tt := range tests {
+		if price, ok := static.Price(tt.item); ok != tt.ok || price.Value != tt.want {
+			t.Errorf("Price(%+v) = %d, %v, want %d, %v", tt.item, price.Value, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, err := ReadCSV(strings.NewReader("T4_BAG,2500\nT5_BAG,lots\n")); err == nil {
+		t.Error("price that is not a number was accepted")
+	}
+}
+
+func TestChain(t *testing.T) {
+	static, _ := ReadCSV(strings.NewReader("T4_BAG,2500\n"))
+	cache, _ := NewCache("", 0)
+
+	cache.Set(bag, Price{Value: 1800, Source: "game"})
+	cache.Set(items.Item{Index: 7}, Price{Value: 90, Source: "game"})
+
+	chain := Chain{static, nil, cache}
+
+	if price, _ := chain.Price(bag); price.Value != 2500 || price.Source != "static" {
+		t.Errorf("bag priced at %+v, static price should win", price)
+	}
+
+	if price, _ := chain.Price(items.Item{Index: 7}); price.Value != 90 {
+		t.Errorf("#7 priced at %+v", price)
+	}
+
+	if _, ok := chain.Price(items.Item{Index: 8}); ok {
+		t.Error("unknown item has a price")
+	}
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+
+	cache, err := NewCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache.Set(bag, Price{Value: 1800, Source: "game"})
+	cache.Set(items.Item{UniqueName: "T5_BAG"}, Price{Value: 5000, Source: "api", Updated: time.Now().Add(-2 * time.Hour)})
+
+	if _, ok := cache.Price(items.Item{UniqueName: "T5_BAG"}); ok {
+		t.Error("expired price was returned")
+	}
+
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if price, ok := reloaded.Price(bag); !ok || price.Value != 1800 || price.Source != "game" {
+		t.Errorf("reloaded price %+v, %v", price, ok)
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	requests := make(chan string, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.URL.Path
+		_, _ = w.Write([]byte(`[
+			{"item_id": "T4_BAG", "city": "Caerleon", "sell_price_min": 0},
+			{"item_id": "T4_BAG", "city": "Lymhurst", "sell_price_min": 2300},
+			{"item_id": "T4_BAG", "city": "Martlock", "sell_price_min": 2100}
+		]`))
+	}))
+	defer server.Close()
+
+	cache, _ := NewCache("", 0)
+	api := NewHTTPProvider(server.URL+"/", cache)
+	defer api.Close()
+
+	if _, ok := api.Price(bag); ok {
+		t.Fatal("price before the lookup finished")
+	}
+
+	// Asking again while the lookup is running doesn't queue it twice
+	api.Price(bag)
+
+	if path := <-requests; path != "/T4_BAG.json" {
+		t.Errorf("requested %s", path)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if price, ok := api.Price(bag); ok {
+			if price.Value != 2100 || price.Source != "api" {
+				t.Errorf("price %+v, want the lowest sell order", price)
+			}
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("lookup never finished")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(requests) != 0 {
+		t.Errorf("%d extra requests", len(requests))
+	}
+}
@@ -5,8 +5,29 @@ import (
 	"log"
 	"reflect"
 	"runtime/debug"
+	"time"
 )
 
+// ticksAtUnixEpoch The .NET ticks of 1970-01-01, ticks count 100ns since 0001-01-01
+const ticksAtUnixEpoch = 621355968000000000
+
+// fixedPointScale Silver, fame and market values are sent in 1/10000 of a unit
+const fixedPointScale = 10000
+
+// DecodeFixedPoint Turns a silver, fame or market value the game sends into whole units, fractions are kept
+func DecodeFixedPoint(value int64) float64 {
+	return float64(value) / fixedPointScale
+}
+
+// DecodeTime Turns the .NET ticks the game sends into a time, zero ticks stay the zero time
+func DecodeTime(ticks int64) time.Time {
+	if ticks == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, (ticks-ticksAtUnixEpoch)*100).UTC()
+}
+
 func DecodeCharacterID(array []int8) uuid.UUID {
 	b := make([]byte, len(array))
 	for i, v := range array {
@@ -0,0 +1,21 @@
+package protocol
+
+import (
+	"github.com/google/uuid"
+)
+
+// EncodeCharacterID Turns a UUID into the byte order the game sends, the inverse of DecodeCharacterID
+func EncodeCharacterID(id uuid.UUID) []int8 {
+	b := id
+
+	b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+	b[4], b[5] = b[5], b[4]
+	b[6], b[7] = b[7], b[6]
+
+	array := make([]int8, len(b))
+	for i, v := range b {
+		array[i] = int8(v)
+	}
+
+	return array
+}
@@ -7,4 +7,6 @@ import (
 var (
 	NoConstructor          = errors.New("no constructor")
 	EncryptionNotSupported = errors.New("encryption not supported")
+	MalformedData          = errors.New("data is malformed")
+	LengthOutOfRange       = errors.New("length exceeds remaining data")
 )
@@ -0,0 +1,10 @@
+package packets
+
+// EvHealthUpdate Change is the health change of the object caused by CauserId, negative for damage
+type EvHealthUpdate struct {
+	ObjectId  int     `albion:"0"`
+	Timestamp int64   `albion:"1"`
+	Change    float32 `albion:"2"`
+	Health    float32 `albion:"3"`
+	CauserId  int     `albion:"6"`
+}
@@ -0,0 +1,119 @@
+package packets
+
+import (
+	"M00DSWINGS/protocol"
+	"M00DSWINGS/protocol/photon"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Decoder Packets implementing it decode their parameters themselves instead of through albion tags
+type Decoder interface {
+	Decode(params photon.ReliableMessageParamaters)
+}
+
+// Decode Fills the fields of the packet from the parameters named by their albion tags, the first tag present in
+// the parameters wins. Fields whose parameter has another type are left alone and reported in the error, the
+// other fields are still filled in.
+func Decode(params photon.ReliableMessageParamaters, packet interface{}) (err error) {
+	if params == nil || packet == nil {
+		return nil
+	}
+
+	if decoder, ok := packet.(Decoder); ok {
+		decoder.Decode(params)
+		return nil
+	}
+
+	valueOf := reflect.ValueOf(packet)
+	if valueOf.Kind() != reflect.Pointer || valueOf.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("packet %T is not a pointer to a struct", packet)
+	}
+
+	val := valueOf.Elem()
+	typ := val.Type()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Join(err, fmt.Errorf("%s: %v", typ.Name(), r))
+		}
+	}()
+
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		target := typ.Field(i)
+
+		if _, ok := target.Tag.Lookup("albion"); !ok {
+			continue
+		}
+
+		if !field.CanSet() {
+			err = errors.Join(err, fmt.Errorf("cannot set field %s.%s", typ.Name(), target.Name))
+			continue
+		}
+
+		for _, tag := range strings.Split(target.Tag.Get("albion"), ",") {
+			num, convErr := strconv.Atoi(strings.TrimSpace(tag))
+			if convErr != nil {
+				err = errors.Join(err, fmt.Errorf("invalid tag value %s for field %s.%s: %w", tag, typ.Name(), target.Name, convErr))
+				continue
+			}
+
+			v, ok := params[uint8(num)]
+			if !ok {
+				continue
+			}
+
+			v = decodeField(field.Type(), v)
+
+			if field.Kind() == reflect.String {
+				if data, ok := target.Tag.Lookup("not-contains"); ok {
+					if s, ok := v.(string); ok && strings.Contains(s, data) {
+						continue
+					}
+				}
+			}
+
+			value := reflect.ValueOf(v)
+
+			if value.IsValid() && field.Type() == value.Type() {
+				field.Set(value)
+				break
+			}
+
+			err = errors.Join(err, fmt.Errorf("type mismatch for field %s.%s: expected %s, got %T", typ.Name(), target.Name, field.Type(), v))
+		}
+	}
+
+	return err
+}
+
+// decodeField Converts the parameter into the type of the field where the game's type differs from it
+func decodeField(typ reflect.Type, v interface{}) interface{} {
+	switch {
+	case typ.Kind() == reflect.Int64:
+		return protocol.DecodeInt64(v)
+	case typ.Kind() == reflect.Int:
+		return protocol.DecodeInteger(v)
+	case typ == reflect.TypeOf([]int8{}) || typ == reflect.TypeOf([]int{}):
+		return protocol.DecodeIntegers(v)
+	case typ == reflect.TypeOf([]int64{}):
+		return protocol.DecodeIntegers64(v)
+	case typ == reflect.TypeOf(uuid.Nil):
+		return protocol.DecodeCharacterID(v.([]int8))
+	case typ == reflect.TypeOf([]uuid.UUID{}):
+		var ids []uuid.UUID
+
+		for _, id := range v.([][]int8) {
+			ids = append(ids, protocol.DecodeCharacterID(id))
+		}
+
+		return ids
+	default:
+		return v
+	}
+}
@@ -0,0 +1,73 @@
+package packets
+
+import (
+	"M00DSWINGS/protocol"
+	"M00DSWINGS/protocol/photon"
+	"fmt"
+	"github.com/google/uuid"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Encode Turns a packet struct back into the parameters the game would send for it, using the first
+// number of every albion tag. It is the inverse of the decoding the Logger does and is meant for
+// generating synthetic traffic.
+func Encode(packet interface{}) (photon.ReliableMessageParamaters, error) {
+	val := reflect.Indirect(reflect.ValueOf(packet))
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("packet %T is not a struct", packet)
+	}
+
+	typ := val.Type()
+	params := make(photon.ReliableMessageParamaters)
+
+	for i := 0; i < val.NumField(); i++ {
+		target := typ.Field(i)
+
+		tag, ok := target.Tag.Lookup("albion")
+		if !ok {
+			continue
+		}
+
+		num, err := strconv.Atoi(strings.TrimSpace(strings.Split(tag, ",")[0]))
+		if err != nil || num < 0 || num > 255 {
+			return nil, fmt.Errorf("invalid tag value %s for field %s.%s", tag, typ.Name(), target.Name)
+		}
+
+		value, err := encodeField(val.Field(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s: %w", typ.Name(), target.Name, err)
+		}
+
+		params[uint8(num)] = value
+	}
+
+	return params, nil
+}
+
+func encodeField(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case uuid.UUID:
+		return protocol.EncodeCharacterID(v), nil
+	case []uuid.UUID:
+		ids := make([][]int8, len(v))
+		for i, id := range v {
+			ids[i] = protocol.EncodeCharacterID(id)
+		}
+		return ids, nil
+	case int:
+		return int32(v), nil
+	case []int:
+		array := make([]int32, len(v))
+		for i, item := range v {
+			array[i] = int32(item)
+		}
+		return array, nil
+	case int8, int16, int32, int64, float32, float64, bool, string,
+		[]int8, []int16, []int32, []int64, []float32, []string, []bool:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("type %T can't be encoded", value)
+	}
+}
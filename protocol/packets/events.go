@@ -12,7 +12,9 @@ type EvLeave struct {
 	Id int64 `albion:"0"`
 }
 
+// EvNewCharacter ObjectId is the ID of the character in the cluster
 type EvNewCharacter struct {
+	ObjectId     int       `albion:"0"`
 	PlayerUID    uuid.UUID `albion:"7"`
 	PlayerName   string    `albion:"1"`
 	GuildName    string    `albion:"8"`
@@ -0,0 +1,161 @@
+package packets
+
+import (
+	"M00DSWINGS/protocol/photon"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+)
+
+var (
+	triky313 = []int8{-45, -35, 124, 14, -23, 103, -41, 74, -71, 66, 67, 20, -12, 60, 44, -101}
+	bruno313 = []int8{-118, 61, -70, 72, 17, -107, 121, 72, -102, 110, 20, -25, 64, 20, 106, 2}
+)
+
+// TestDecodeCapturedPayloads Decodes the payloads captured next to the codes in protocol/enums into the packets
+// the registry decodes them to
+func TestDecodeCapturedPayloads(t *testing.T) {
+	tests := []struct {
+		name   string
+		params photon.ReliableMessageParamaters
+		packet interface{}
+		want   interface{}
+	}{
+		{
+			"update money",
+			photon.ReliableMessageParamaters{0: int16(4195), 1: int64(884995625105), 252: int8(71)},
+			&EvUpdateMoney{},
+			&EvUpdateMoney{ObjectId: 4195, Silver: 884995625105},
+		},
+		{
+			"update fame",
+			photon.ReliableMessageParamaters{0: int16(4195), 1: int64(5811910006347), 2: int32(100000000), 4: int16(10000), 6: int8(1), 7: int16(427), 252: int8(72)},
+			&EvUpdateFame{},
+			&EvUpdateFame{ObjectId: 4195, TotalFame: 5811910006347, Gained: 100000000},
+		},
+		{
+			"take silver",
+			photon.ReliableMessageParamaters{0: int8(-57), 1: int32(2178162), 2: int8(-57), 3: int32(10000000), 8: int16(10000), 252: int8(55)},
+			&EvTakeSilver{},
+			&EvTakeSilver{ObjectId: -57, Amount: 10000000},
+		},
+		{
+			"party joined",
+			photon.ReliableMessageParamaters{0: int16(14368), 2: int8(1), 3: triky313, 4: [][]int8{triky313, bruno313}, 5: []string{"Triky313", "Bruno313"}, 252: int16(212)},
+			&EvPartyJoined{},
+			&EvPartyJoined{
+				PartyLeader:     uuid.MustParse("0e7cddd3-67e9-4ad7-b942-4314f43c2c9b"),
+				PlayersUuid:     []uuid.UUID{uuid.MustParse("0e7cddd3-67e9-4ad7-b942-4314f43c2c9b"), uuid.MustParse("48ba3d8a-9511-4879-9a6e-14e740146a02")},
+				PlayerUsernames: []string{"Triky313", "Bruno313"},
+			},
+		},
+		{
+			"party player joined",
+			photon.ReliableMessageParamaters{0: int16(11925), 1: []int8{-63, -19, 39, 16, 26, 35, -25, 67, -111, 60, -87, -58, -31, -100, -124, -44}, 2: "Mitch77", 3: int8(1), 4: int8(20), 5: int8(12), 6: int8(-1), 7: true, 252: int16(214)},
+			&EvPartySinglePlayerJoined{},
+			&EvPartySinglePlayerJoined{PlayerUID: uuid.MustParse("1027edc1-231a-43e7-913c-a9c6e19c84d4"), PlayerName: "Mitch77"},
+		},
+		{
+			"party player left",
+			photon.ReliableMessageParamaters{0: int16(14368), 1: triky313, 252: int16(216)},
+			&EvPartyLeft{},
+			&EvPartyLeft{PlayerUID: uuid.MustParse("0e7cddd3-67e9-4ad7-b942-4314f43c2c9b")},
+		},
+		{
+			"party leader changed",
+			photon.ReliableMessageParamaters{0: int16(14595), 1: triky313, 252: int16(217)},
+			&EvPartyLeaderChanged{},
+			&EvPartyLeaderChanged{NewPartyLeader: uuid.MustParse("0e7cddd3-67e9-4ad7-b942-4314f43c2c9b")},
+		},
+		{
+			"party loot setting changed",
+			photon.ReliableMessageParamaters{0: int16(14368), 1: int8(1), 252: int16(218)},
+			&EvPartyLootSettingChanged{},
+			&EvPartyLootSettingChanged{LootSetting: 1},
+		},
+		{
+			"party role flag",
+			photon.ReliableMessageParamaters{0: int8(8), 1: bruno313, 252: int16(225)},
+			&EvPartySetRoleFlag{},
+			&EvPartySetRoleFlag{Flag: 8, PlayerUID: uuid.MustParse("48ba3d8a-9511-4879-9a6e-14e740146a02")},
+		},
+		{
+			"other grabbed loot",
+			photon.ReliableMessageParamaters{0: int16(424), 1: "Triky313", 2: "Bruno313", 4: int16(1841), 5: int8(1), 252: int16(256)},
+			&EvOtherGrabbedLoot{},
+			&EvOtherGrabbedLoot{LootedFromName: "Triky313", LooterByName: "Bruno313", ItemIndex: 1841, Quantity: 1},
+		},
+		{
+			"other grabbed silver",
+			photon.ReliableMessageParamaters{0: int16(6436), 2: "Triky313", 3: true, 5: int32(1550115), 252: int16(256)},
+			&EvOtherGrabbedLoot{},
+			&EvOtherGrabbedLoot{LooterByName: "Triky313", IsSilver: true, Quantity: 1550115},
+		},
+		{
+			"new loot",
+			photon.ReliableMessageParamaters{0: int16(1863), 2: int16(1853), 3: "Dicky", 4: []float32{236.79169, -185.40233}, 5: float32(42.864536), 6: true, 7: int8(1)},
+			&EvNewLoot{},
+			&EvNewLoot{Id: 1863, Owner: "Dicky"},
+		},
+		{
+			"new simple item",
+			photon.ReliableMessageParamaters{0: int16(505), 1: int16(7006), 2: int8(1), 3: true, 4: int32(29033970), 252: int8(27)},
+			&EvNewSimpleItem{},
+			&EvNewSimpleItem{Id: 505, ItemIndex: 7006, Quantity: 1, MarketValue: 29033970},
+		},
+		{
+			"attach item container",
+			photon.ReliableMessageParamaters{0: int8(78), 1: []int8{-99, -50, 125, -49, 86, 0, -115, 74, -74, 67, 9, 101, -87, -71, -66, -10}, 3: []int16{0, 0, 0, 0, 0, 0, 656, 657}, 4: int8(8), 252: int8(89)},
+			&EvAttachItemContainer{},
+			&EvAttachItemContainer{Id: 78, ContainerUUID: uuid.MustParse("cf7dce9d-0056-4a8d-b643-0965a9b9bef6"), Items: []int{0, 0, 0, 0, 0, 0, 656, 657}, Slots: 8},
+		},
+		{
+			"detach item container",
+			photon.ReliableMessageParamaters{0: []int8{-95, 72, -77, -75, -70, 34, 127, 73, -114, -96, 28, 8, 75, -107, -106, 125}, 252: int8(90)},
+			&EvDetachItemContainer{},
+			&EvDetachItemContainer{ContainerUUID: uuid.MustParse("b5b348a1-22ba-497f-8ea0-1c084b95967d")},
+		},
+		{
+			"inventory put item",
+			photon.ReliableMessageParamaters{0: int16(652), 1: int8(6), 2: []int8{118, -97, 114, 112, -46, 84, -60, 75, -103, -93, -3, -29, 118, -125, -50, 96}, 3: int8(17), 252: int8(23)},
+			&EvInventoryPutItems{},
+			&EvInventoryPutItems{ObjectId: 652, SlotId: 6, ContainerId: uuid.MustParse("70729f76-54d2-4bc4-99a3-fde37683ce60")},
+		},
+		{
+			"new loot chest",
+			photon.ReliableMessageParamaters{0: int8(23), 1: []float32{20.5, 177.5}, 2: int16(423), 3: "KEEPER_SOLO_BOOKCHEST_STANDARD", 4: "FOREST_GREEN_LOOTCHEST_KEEPER_SOLO_BOOKCHEST_STANDARD", 5: int8(4), 6: int64(637734315213820408), 13: true, 252: int16(367)},
+			&EvNewLootChest{},
+			&EvNewLootChest{Id: 23, Owner: "KEEPER_SOLO_BOOKCHEST_STANDARD"},
+		},
+		{
+			"update loot chest",
+			photon.ReliableMessageParamaters{0: int16(4769), 1: int8(5), 2: int64(637927794424868192), 3: [][]int8{triky313}, 4: [][]int8{triky313}, 6: true, 7: true, 8: float32(2.6), 9: true, 252: int16(371)},
+			&EvUpdateLootChest{},
+			&EvUpdateLootChest{Id: 4769},
+		},
+		{
+			"inventory move item",
+			photon.ReliableMessageParamaters{0: int8(4), 1: []int8{39, -87, 28, -11, -124, -89, 51, 72, -111, -18, 117, 74, 87, 91, -56, 72}, 2: int8(14), 4: []int8{39, -87, 28, -11, -124, -89, 51, 72, -111, -18, 117, 74, 87, 91, -56, 72}, 5: int8(14), 253: int8(29)},
+			&OpInventoryMoveItems{},
+			&OpInventoryMoveItems{FromSlot: 4, FromUUID: uuid.MustParse("f51ca927-a784-4833-91ee-754a575bc848"), ToUUID: uuid.MustParse("f51ca927-a784-4833-91ee-754a575bc848")},
+		},
+		{
+			"cluster change response",
+			photon.ReliableMessageParamaters{0: "4000", 253: int16(36), 255: int16(114)},
+			&OpClusterChange{},
+			&OpClusterChange{Cluster: "4000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Decode(tt.params, tt.packet); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tt.packet, tt.want) {
+				t.Errorf("got %+v, want %+v", tt.packet, tt.want)
+			}
+		})
+	}
+}
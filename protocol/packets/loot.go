@@ -12,10 +12,13 @@ type EvNewLoot struct {
 	Owner string `albion:"3"`
 }
 
+// EvNewSimpleItem MarketValue is the game's own estimate of the item in 1/10000 silver like every silver amount
+// the game sends
 type EvNewSimpleItem struct {
-	Id        int `albion:"0"`
-	ItemIndex int `albion:"1"`
-	Quantity  int `albion:"2"`
+	Id          int   `albion:"0"`
+	ItemIndex   int   `albion:"1"`
+	Quantity    int   `albion:"2"`
+	MarketValue int64 `albion:"4"`
 }
 
 type EvAttachItemContainer struct {
@@ -0,0 +1,20 @@
+package packets
+
+// EvUpdateFame Fame the player gained, Gained includes the zone multiplier. Fame is in 1/10000 like silver.
+type EvUpdateFame struct {
+	ObjectId  int   `albion:"0"`
+	TotalFame int64 `albion:"1"`
+	Gained    int64 `albion:"2"`
+}
+
+// EvUpdateMoney The silver the player holds after a change, in 1/10000 silver
+type EvUpdateMoney struct {
+	ObjectId int   `albion:"0"`
+	Silver   int64 `albion:"1"`
+}
+
+// EvTakeSilver Silver the player picked up, Amount is in 1/10000 silver
+type EvTakeSilver struct {
+	ObjectId int   `albion:"0"`
+	Amount   int64 `albion:"3"`
+}
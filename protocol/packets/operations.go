@@ -2,14 +2,18 @@ package packets
 
 import "github.com/google/uuid"
 
+// OpJoinGame ObjectId is the ID of the player's character in the cluster
 type OpJoinGame struct {
+	ObjectId      int       `albion:"0"`
 	CharacterID   uuid.UUID `albion:"1"`
 	CharacterName string    `albion:"2"`
 	GuildID       uuid.UUID `albion:"53"`
 	GuildName     string    `albion:"57"`
 	AllianceName  string    `albion:"77"`
+	Cluster       string    `albion:"8"`
 }
 
+// OpClusterChange Cluster is the ID of the zone entered, only the response carries it
 type OpClusterChange struct {
 	Cluster string `albion:"0"`
 }
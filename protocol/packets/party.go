@@ -23,3 +23,14 @@ type EvPartyLeaderChanged struct {
 
 type EvPartyDisbanded struct {
 }
+
+// EvPartyLootSettingChanged The loot setting of the player the client belongs to, the event names no player
+type EvPartyLootSettingChanged struct {
+	LootSetting int `albion:"1"`
+}
+
+// EvPartySetRoleFlag Flag is the role flag of the member, e.g. 8
+type EvPartySetRoleFlag struct {
+	Flag      int       `albion:"0"`
+	PlayerUID uuid.UUID `albion:"1"`
+}
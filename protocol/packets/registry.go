@@ -0,0 +1,70 @@
+package packets
+
+import "M00DSWINGS/protocol/enums"
+
+// Operation Ties an operation code to the packet its parameters are decoded into
+type Operation struct {
+	Type   enums.OperationType
+	Packet interface{}
+}
+
+// Event Ties an event code to the packet its parameters are decoded into
+type Event struct {
+	Type   enums.EventType
+	Packet interface{}
+}
+
+// Operations Every operation the logger decodes, the simulator builds its packets from the same table
+var Operations = []Operation{
+	{enums.OpTypeJoin, OpJoinGame{}},
+
+	// Loot operations
+	{enums.OpTypeInventoryMoveItem, OpInventoryMoveItems{}},
+}
+
+// Responses Operations only the response of which carries the packet, their requests are skipped
+var Responses = []Operation{
+	{enums.OpTypeChangeCluster, OpClusterChange{}},
+}
+
+// Events Every event the logger decodes, the simulator builds its packets from the same table
+var Events = []Event{
+
+	// Combat events
+	{enums.EventTypeHealthUpdate, EvHealthUpdate{}},
+
+	// Fame and silver events
+	{enums.EventTypeUpdateFame, EvUpdateFame{}},
+	{enums.EventTypeUpdateMoney, EvUpdateMoney{}},
+	{enums.EventTypeTakeSilver, EvTakeSilver{}},
+
+	// Party events
+	{enums.EventTypePartyPlayerJoined, EvPartySinglePlayerJoined{}},
+	{enums.EventTypePartyJoined, EvPartyJoined{}},
+	{enums.EventTypePartyPlayerLeft, EvPartyLeft{}},
+	{enums.EventTypeNewCharacter, EvNewCharacter{}},
+	{enums.EventTypeCharacterStats, EvCharacterStats{}},
+	{enums.EventTypePartyLeaderChanged, EvPartyLeaderChanged{}},
+	{enums.EventTypePartyDisbanded, EvPartyDisbanded{}},
+
+	// Log Party events
+	{enums.EventTypePartyReadyCheckUpdate, EvPartyReadyCheck{}},
+	{enums.EventTypePartyLootSettingChangedPlayer, EvPartyLootSettingChanged{}},
+	{enums.EventTypePartySetRoleFlag, EvPartySetRoleFlag{}},
+	{enums.EventTypePartyPlayerUpdated, Logger{}},
+	{enums.EventTypePartyInvitationAnswer, Logger{}},
+	{enums.EventTypePartyJoinRequestAnswer, Logger{}},
+	{enums.EventTypePartyLootItems, Logger{}},
+	{enums.EventTypePartyLootItemsRemoved, Logger{}},
+
+	// Loot events
+	{enums.EventTypeNewSimpleItem, EvNewSimpleItem{}},
+	{enums.EventTypeNewLootChest, EvNewLootChest{}},
+	{enums.EventTypeNewLoot, EvNewLoot{}},
+	{enums.EventTypeAttachItemContainer, EvAttachItemContainer{}},
+	{enums.EventTypeDetachItemContainer, EvDetachItemContainer{}},
+	{enums.EventTypeUpdateLootChest, EvUpdateLootChest{}},
+	{enums.EventTypeLeave, EvLeave{}},
+	{enums.EventTypeOtherGrabbedLoot, EvOtherGrabbedLoot{}},
+	{enums.EventTypeInventoryPutItem, EvInventoryPutItems{}},
+}
@@ -1,9 +1,12 @@
 package photon
 
 import (
+	"M00DSWINGS/protocol"
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
+	"reflect"
 )
 
 const (
@@ -31,12 +34,43 @@ const (
 
 type ReliableMessageParamaters map[uint8]interface{}
 
+// CustomData Holds a Protocol16 custom type which is left to the caller to interpret
+type CustomData struct {
+	TypeCode uint8
+	Data     []byte
+}
+
+// EventData Holds an event nested inside a parameter
+type EventData struct {
+	Code       uint8
+	Parameters ReliableMessageParamaters
+}
+
+// OperationRequestData Holds an operation request nested inside a parameter
+type OperationRequestData struct {
+	OperationCode uint8
+	Parameters    ReliableMessageParamaters
+}
+
+// OperationResponseData Holds an operation response nested inside a parameter
+type OperationResponseData struct {
+	OperationCode         uint8
+	OperationResponseCode int16
+	OperationDebugString  string
+	Parameters            ReliableMessageParamaters
+}
+
 // DecodeReliableMessage Converts the parameters of a reliable message into a hash suitable for use in
 func DecodeReliableMessage(msg ReliableMessage) (ReliableMessageParamaters, error) {
 	buf := bytes.NewBuffer(msg.Data)
+
+	return decodeParameters(buf, int(msg.ParamaterCount))
+}
+
+func decodeParameters(buf *bytes.Buffer, count int) (ReliableMessageParamaters, error) {
 	params := make(map[uint8]interface{})
 
-	for i := 0; i < int(msg.ParamaterCount); i++ {
+	for i := 0; i < count; i++ {
 		var paramID uint8
 		var paramType uint8
 
@@ -68,6 +102,8 @@ func decodeType(buf *bytes.Buffer, paramType uint8) (interface{}, error) {
 		return decodeInt8Type(buf) // buffer
 	case Float32Type:
 		return decodeFloat32Type(buf) // buffer
+	case DoubleType:
+		return decodeDoubleType(buf) // buffer
 	case Int32Type:
 		return decodeInt32Type(buf) // buffer
 	case Int16Type, 7:
@@ -80,31 +116,87 @@ func decodeType(buf *bytes.Buffer, paramType uint8) (interface{}, error) {
 		result, err := decodeBooleanType(buf)
 
 		if err != nil {
-			return nil, fmt.Errorf("ERROR - Boolean - %v", err.Error())
+			return nil, fmt.Errorf("ERROR - Boolean - %w", err)
 		} else {
 			return result, nil
 		}
 	case Int8SliceType:
 		result, err := decodeSliceInt8Type(buf)
 		if err != nil {
-			return nil, fmt.Errorf("ERROR - Slice Int8 - %v", err.Error())
+			return nil, fmt.Errorf("ERROR - Slice Int8 - %w", err)
+		} else {
+			return result, nil
+		}
+	case Int32SliceType:
+		result, err := decodeSliceInt32Type(buf)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR - Slice Int32 - %w", err)
+		} else {
+			return result, nil
+		}
+	case StringSliceType:
+		result, err := decodeSliceStringType(buf)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR - Slice String - %w", err)
+		} else {
+			return result, nil
+		}
+	case ObjectSliceType:
+		result, err := decodeSliceObjectType(buf)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR - Slice Object - %w", err)
 		} else {
 			return result, nil
 		}
 	case SliceType:
 		array, err := decodeSlice(buf)
 		if err != nil {
-			return nil, fmt.Errorf("ERROR - Slice - %v", err.Error())
+			return nil, fmt.Errorf("ERROR - Slice - %w", err)
 		} else {
 			return array, nil
 		}
 	case DictionaryType:
 		dict, err := decodeDictionaryType(buf)
 		if err != nil {
-			return nil, fmt.Errorf("ERROR - Dictionary - %v", err.Error())
+			return nil, fmt.Errorf("ERROR - Dictionary - %w", err)
 		} else {
 			return dict, nil
 		}
+	case Hashtable:
+		table, err := decodeHashtableType(buf)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR - Hashtable - %w", err)
+		} else {
+			return table, nil
+		}
+	case Custom:
+		custom, err := decodeCustomType(buf)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR - Custom - %w", err)
+		} else {
+			return custom, nil
+		}
+	case EventDateType:
+		event, err := decodeEventDataType(buf)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR - Event - %w", err)
+		} else {
+			return event, nil
+		}
+	case OperationRequestType:
+		request, err := decodeOperationRequestType(buf)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR - Operation Request - %w", err)
+		} else {
+			return request, nil
+		}
+	case OperationResponseType:
+		response, err := decodeOperationResponseType(buf)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR - Operation Response - %w", err)
+		} else {
+			return response, nil
+		}
 	default:
 		return nil, fmt.Errorf("ERROR - Invalid type of %v", paramType)
 	}
@@ -122,7 +214,24 @@ func decodeSlice(buf *bytes.Buffer) (interface{}, error) {
 		return nil, err
 	}
 
+	// Every element takes at least one byte, anything longer can't be read anyway
+	if int(length) > buf.Len() {
+		return nil, fmt.Errorf("%w: slice of %d elements, %d bytes left", protocol.LengthOutOfRange, length, buf.Len())
+	}
+
 	switch sliceType {
+	case Int8Type:
+		array := make([]int8, length)
+
+		for j := 0; j < int(length); j++ {
+			temp, err := decodeInt8Type(buf)
+			if err != nil {
+				return nil, err
+			}
+			array[j] = temp
+		}
+
+		return array, nil
 	case Float32Type:
 		array := make([]float32, length)
 
@@ -134,6 +243,18 @@ func decodeSlice(buf *bytes.Buffer) (interface{}, error) {
 			array[j] = temp
 		}
 
+		return array, nil
+	case DoubleType:
+		array := make([]float64, length)
+
+		for j := 0; j < int(length); j++ {
+			temp, err := decodeDoubleType(buf)
+			if err != nil {
+				return nil, err
+			}
+			array[j] = temp
+		}
+
 		return array, nil
 	case Int32Type:
 		array := make([]int32, length)
@@ -208,6 +329,40 @@ func decodeSlice(buf *bytes.Buffer) (interface{}, error) {
 			array[j] = result
 		}
 
+		return array, nil
+	case Custom:
+		var typeCode uint8
+		if err := binary.Read(buf, binary.BigEndian, &typeCode); err != nil {
+			return nil, err
+		}
+
+		array := make([]CustomData, length)
+
+		for j := 0; j < int(length); j++ {
+			data, err := decodeCustomData(buf)
+			if err != nil {
+				return nil, err
+			}
+			array[j] = CustomData{TypeCode: typeCode, Data: data}
+		}
+
+		return array, nil
+	case DictionaryType:
+		keyTypeCode, valueTypeCode, err := decodeDictionaryTypeCodes(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]map[interface{}]interface{}, length)
+
+		for j := 0; j < int(length); j++ {
+			dict, err := decodeDictionaryElements(buf, keyTypeCode, valueTypeCode)
+			if err != nil {
+				return nil, err
+			}
+			array[j] = dict
+		}
+
 		return array, nil
 	case SliceType:
 		array := make([]interface{}, length)
@@ -223,8 +378,21 @@ func decodeSlice(buf *bytes.Buffer) (interface{}, error) {
 		}
 
 		return array, nil
-	default:
+	case NilType, 0:
 		return nil, fmt.Errorf("invalid slice type of %d", sliceType)
+	default:
+		// Every other type is stored without a type code per element
+		array := make([]interface{}, length)
+
+		for j := 0; j < int(length); j++ {
+			value, err := decodeType(buf, sliceType)
+			if err != nil {
+				return nil, err
+			}
+			array[j] = value
+		}
+
+		return array, nil
 	}
 }
 
@@ -238,6 +406,11 @@ func decodeFloat32Type(buf *bytes.Buffer) (temp float32, err error) {
 	return
 }
 
+func decodeDoubleType(buf *bytes.Buffer) (temp float64, err error) {
+	err = binary.Read(buf, binary.BigEndian, &temp)
+	return
+}
+
 func decodeInt16Type(buf *bytes.Buffer) (temp int16, err error) {
 	err = binary.Read(buf, binary.BigEndian, &temp)
 	return
@@ -261,8 +434,12 @@ func decodeStringType(buf *bytes.Buffer) (string, error) {
 		return "", err
 	}
 
+	if int(length) > buf.Len() {
+		return "", fmt.Errorf("%w: string of %d bytes, %d bytes left", protocol.LengthOutOfRange, length, buf.Len())
+	}
+
 	strBytes := make([]byte, length)
-	_, err = buf.Read(strBytes)
+	_, err = io.ReadFull(buf, strBytes)
 	if err != nil {
 		return "", err
 	}
@@ -295,6 +472,10 @@ func decodeSliceInt8Type(buf *bytes.Buffer) ([]int8, error) {
 		return nil, err
 	}
 
+	if uint64(length) > uint64(buf.Len()) {
+		return nil, fmt.Errorf("%w: slice of %d bytes, %d bytes left", protocol.LengthOutOfRange, length, buf.Len())
+	}
+
 	array := make([]int8, length)
 
 	err = binary.Read(buf, binary.BigEndian, array)
@@ -306,28 +487,38 @@ func decodeSliceInt8Type(buf *bytes.Buffer) ([]int8, error) {
 }
 
 func decodeDictionaryType(buf *bytes.Buffer) (map[interface{}]interface{}, error) {
-	var keyTypeCode uint8
-	var valueTypeCode uint8
-	var dictionarySize uint16
-
-	// Read the key type code
-	err := binary.Read(buf, binary.BigEndian, &keyTypeCode)
+	keyTypeCode, valueTypeCode, err := decodeDictionaryTypeCodes(buf)
 	if err != nil {
 		return nil, err
 	}
 
+	return decodeDictionaryElements(buf, keyTypeCode, valueTypeCode)
+}
+
+func decodeDictionaryTypeCodes(buf *bytes.Buffer) (keyTypeCode uint8, valueTypeCode uint8, err error) {
+	// Read the key type code
+	if err = binary.Read(buf, binary.BigEndian, &keyTypeCode); err != nil {
+		return
+	}
+
 	// Read the value type code
 	err = binary.Read(buf, binary.BigEndian, &valueTypeCode)
-	if err != nil {
-		return nil, err
-	}
+	return
+}
+
+func decodeDictionaryElements(buf *bytes.Buffer, keyTypeCode uint8, valueTypeCode uint8) (map[interface{}]interface{}, error) {
+	var dictionarySize uint16
 
 	// Read the dictionary size
-	err = binary.Read(buf, binary.BigEndian, &dictionarySize)
+	err := binary.Read(buf, binary.BigEndian, &dictionarySize)
 	if err != nil {
 		return nil, err
 	}
 
+	if int(dictionarySize) > buf.Len() {
+		return nil, fmt.Errorf("%w: dictionary of %d entries, %d bytes left", protocol.LengthOutOfRange, dictionarySize, buf.Len())
+	}
+
 	// Initialize the dictionary map
 	dictionary := make(map[interface{}]interface{})
 
@@ -347,6 +538,10 @@ func decodeDictionaryType(buf *bytes.Buffer) (map[interface{}]interface{}, error
 			return nil, err
 		}
 
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			return nil, fmt.Errorf("%w: dictionary key of type %T", protocol.MalformedData, key)
+		}
+
 		// Handle value type code
 		actualValueTypeCode := valueTypeCode
 		if valueTypeCode == 0 || valueTypeCode == 42 {
@@ -367,3 +562,204 @@ func decodeDictionaryType(buf *bytes.Buffer) (map[interface{}]interface{}, error
 
 	return dictionary, nil
 }
+
+func decodeSliceInt32Type(buf *bytes.Buffer) ([]int32, error) {
+	var length uint32
+
+	err := binary.Read(buf, binary.BigEndian, &length)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(length)*4 > uint64(buf.Len()) {
+		return nil, fmt.Errorf("%w: slice of %d integers, %d bytes left", protocol.LengthOutOfRange, length, buf.Len())
+	}
+
+	array := make([]int32, length)
+
+	err = binary.Read(buf, binary.BigEndian, array)
+	if err != nil {
+		return nil, err
+	}
+
+	return array, nil
+}
+
+func decodeSliceStringType(buf *bytes.Buffer) ([]string, error) {
+	var length uint16
+
+	err := binary.Read(buf, binary.BigEndian, &length)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each string has at least its two bytes of length
+	if int(length)*2 > buf.Len() {
+		return nil, fmt.Errorf("%w: slice of %d strings, %d bytes left", protocol.LengthOutOfRange, length, buf.Len())
+	}
+
+	array := make([]string, length)
+
+	for i := 0; i < int(length); i++ {
+		array[i], err = decodeStringType(buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return array, nil
+}
+
+func decodeSliceObjectType(buf *bytes.Buffer) ([]interface{}, error) {
+	var length uint16
+
+	err := binary.Read(buf, binary.BigEndian, &length)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(length) > buf.Len() {
+		return nil, fmt.Errorf("%w: slice of %d objects, %d bytes left", protocol.LengthOutOfRange, length, buf.Len())
+	}
+
+	array := make([]interface{}, length)
+
+	for i := 0; i < int(length); i++ {
+		var typeCode uint8
+		if err := binary.Read(buf, binary.BigEndian, &typeCode); err != nil {
+			return nil, err
+		}
+
+		array[i], err = decodeType(buf, typeCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return array, nil
+}
+
+// decodeHashtableType Reads a hashtable, unlike dictionaries every key and value carries its own type code
+func decodeHashtableType(buf *bytes.Buffer) (map[interface{}]interface{}, error) {
+	return decodeDictionaryElements(buf, NilType, NilType)
+}
+
+func decodeCustomType(buf *bytes.Buffer) (CustomData, error) {
+	var custom CustomData
+
+	if err := binary.Read(buf, binary.BigEndian, &custom.TypeCode); err != nil {
+		return CustomData{}, err
+	}
+
+	data, err := decodeCustomData(buf)
+	if err != nil {
+		return CustomData{}, err
+	}
+
+	custom.Data = data
+
+	return custom, nil
+}
+
+func decodeCustomData(buf *bytes.Buffer) ([]byte, error) {
+	var length uint16
+
+	if err := binary.Read(buf, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	if int(length) > buf.Len() {
+		return nil, fmt.Errorf("%w: custom type of %d bytes, %d bytes left", protocol.LengthOutOfRange, length, buf.Len())
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(buf, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func decodeParameterTable(buf *bytes.Buffer) (ReliableMessageParamaters, error) {
+	var count uint16
+
+	if err := binary.Read(buf, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+
+	// Every parameter has at least a key and a type code
+	if int(count)*2 > buf.Len() {
+		return nil, fmt.Errorf("%w: %d parameters, %d bytes left", protocol.LengthOutOfRange, count, buf.Len())
+	}
+
+	return decodeParameters(buf, int(count))
+}
+
+func decodeEventDataType(buf *bytes.Buffer) (EventData, error) {
+	var event EventData
+
+	if err := binary.Read(buf, binary.BigEndian, &event.Code); err != nil {
+		return EventData{}, err
+	}
+
+	params, err := decodeParameterTable(buf)
+	if err != nil {
+		return EventData{}, err
+	}
+
+	event.Parameters = params
+
+	return event, nil
+}
+
+func decodeOperationRequestType(buf *bytes.Buffer) (OperationRequestData, error) {
+	var request OperationRequestData
+
+	if err := binary.Read(buf, binary.BigEndian, &request.OperationCode); err != nil {
+		return OperationRequestData{}, err
+	}
+
+	params, err := decodeParameterTable(buf)
+	if err != nil {
+		return OperationRequestData{}, err
+	}
+
+	request.Parameters = params
+
+	return request, nil
+}
+
+func decodeOperationResponseType(buf *bytes.Buffer) (OperationResponseData, error) {
+	var response OperationResponseData
+
+	if err := binary.Read(buf, binary.BigEndian, &response.OperationCode); err != nil {
+		return OperationResponseData{}, err
+	}
+
+	if err := binary.Read(buf, binary.BigEndian, &response.OperationResponseCode); err != nil {
+		return OperationResponseData{}, err
+	}
+
+	var debugType uint8
+	if err := binary.Read(buf, binary.BigEndian, &debugType); err != nil {
+		return OperationResponseData{}, err
+	}
+
+	debug, err := decodeType(buf, debugType)
+	if err != nil {
+		return OperationResponseData{}, err
+	}
+
+	if debugString, ok := debug.(string); ok {
+		response.OperationDebugString = debugString
+	}
+
+	params, err := decodeParameterTable(buf)
+	if err != nil {
+		return OperationResponseData{}, err
+	}
+
+	response.Parameters = params
+
+	return response, nil
+}
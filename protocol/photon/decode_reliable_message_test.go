@@ -0,0 +1,167 @@
+package photon
+
+import (
+	"M00DSWINGS/protocol"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// raw Concatenates big endian encodings of the given values
+func raw(values ...any) []byte {
+	var buf bytes.Buffer
+
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			_ = binary.Write(&buf, binary.BigEndian, uint16(len(v)))
+			buf.WriteString(v)
+		case []byte:
+			buf.Write(v)
+		default:
+			_ = binary.Write(&buf, binary.BigEndian, v)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+type typeTest struct {
+	name     string
+	typeCode uint8
+	data     []byte
+	want     interface{}
+}
+
+// typeTests Returns one encoded value per Protocol16 type code together with its decoded value
+func typeTests() []typeTest {
+	params := raw(uint16(2), uint8(0), uint8(Int16Type), int16(5), uint8(1), uint8(StringType), "inner")
+	decodedParams := ReliableMessageParamaters{0: int16(5), 1: "inner"}
+
+	return []typeTest{
+		{"nil", NilType, nil, nil},
+		{"int8", Int8Type, raw(int8(-3)), int8(-3)},
+		{"int16", Int16Type, raw(int16(-300)), int16(-300)},
+		{"int32", Int32Type, raw(int32(70000)), int32(70000)},
+		{"int64", Int64Type, raw(int64(637926215956544319)), int64(637926215956544319)},
+		{"float32", Float32Type, raw(float32(1.5)), float32(1.5)},
+		{"double", DoubleType, raw(2.25), 2.25},
+		{"bool", BooleanType, raw(uint8(1)), true},
+		{"string", StringType, raw("Triky313"), "Triky313"},
+		{"int8 slice", Int8SliceType, raw(uint32(3), []int8{1, -1, 0}), []int8{1, -1, 0}},
+		{"int32 slice", Int32SliceType, raw(uint32(2), []int32{1, -1}), []int32{1, -1}},
+		{"string slice", StringSliceType, raw(uint16(2), "a", "bc"), []string{"a", "bc"}},
+		{
+			"object slice", ObjectSliceType,
+			raw(uint16(3), uint8(Int8Type), int8(1), uint8(StringType), "two", uint8(NilType)),
+			[]interface{}{int8(1), "two", nil},
+		},
+		{"slice of int8", SliceType, raw(uint16(2), uint8(Int8Type), []int8{4, 5}), []int8{4, 5}},
+		{"slice of int16", SliceType, raw(uint16(2), uint8(Int16Type), []int16{4, 5}), []int16{4, 5}},
+		{"slice of double", SliceType, raw(uint16(1), uint8(DoubleType), 0.5), []float64{0.5}},
+		{"slice of bool", SliceType, raw(uint16(2), uint8(BooleanType), uint8(0), uint8(1)), []bool{false, true}},
+		{
+			"slice of byte slices", SliceType,
+			raw(uint16(2), uint8(Int8SliceType), uint32(1), int8(1), uint32(0)),
+			[][]int8{{1}, {}},
+		},
+		{
+			"slice of slices", SliceType,
+			raw(uint16(1), uint8(SliceType), uint16(1), uint8(StringType), "x"),
+			[]interface{}{[]string{"x"}},
+		},
+		{
+			"slice of custom", SliceType,
+			raw(uint16(2), uint8(Custom), uint8(7), uint16(1), uint8(0xaa), uint16(0)),
+			[]CustomData{{TypeCode: 7, Data: []byte{0xaa}}, {TypeCode: 7, Data: []byte{}}},
+		},
+		{
+			"slice of dictionaries", SliceType,
+			raw(uint16(2), uint8(DictionaryType), uint8(StringType), uint8(Int32Type), uint16(1), "a", int32(1), uint16(0)),
+			[]map[interface{}]interface{}{{"a": int32(1)}, {}},
+		},
+		{
+			"slice of hashtables", SliceType,
+			raw(uint16(1), uint8(Hashtable), uint16(1), uint8(Int16Type), int16(1), uint8(BooleanType), uint8(1)),
+			[]interface{}{map[interface{}]interface{}{int16(1): true}},
+		},
+		{
+			"dictionary", DictionaryType,
+			raw(uint8(StringType), uint8(NilType), uint16(2), "a", uint8(Int8Type), int8(1), "b", uint8(NilType)),
+			map[interface{}]interface{}{"a": int8(1), "b": nil},
+		},
+		{
+			"hashtable", Hashtable,
+			raw(uint16(2), uint8(Int8Type), int8(1), uint8(StringType), "v", uint8(StringType), "k", uint8(DoubleType), 1.0),
+			map[interface{}]interface{}{int8(1): "v", "k": 1.0},
+		},
+		{"custom", Custom, raw(uint8(7), uint16(3), []byte{1, 2, 3}), CustomData{TypeCode: 7, Data: []byte{1, 2, 3}}},
+		{"event", EventDateType, raw(uint8(9), params), EventData{Code: 9, Parameters: decodedParams}},
+		{
+			"operation request", OperationRequestType,
+			raw(uint8(2), params),
+			OperationRequestData{OperationCode: 2, Parameters: decodedParams},
+		},
+		{
+			"operation response", OperationResponseType,
+			raw(uint8(2), int16(-1), uint8(StringType), "oops", params),
+			OperationResponseData{OperationCode: 2, OperationResponseCode: -1, OperationDebugString: "oops", Parameters: decodedParams},
+		},
+	}
+}
+
+func TestDecodeType(t *testing.T) {
+	for _, tt := range typeTests() {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(tt.data)
+
+			got, err := decodeType(buf, tt.typeCode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+
+			if buf.Len() != 0 {
+				t.Errorf("%d bytes left unread", buf.Len())
+			}
+		})
+	}
+}
+
+func TestDecodeTypeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		typeCode uint8
+		data     []byte
+		want     error
+	}{
+		{"string longer than data", StringType, raw(uint16(10), []byte("abc")), protocol.LengthOutOfRange},
+		{"int8 slice longer than data", Int8SliceType, raw(uint32(0xffffffff), int8(1)), protocol.LengthOutOfRange},
+		{"int32 slice longer than data", Int32SliceType, raw(uint32(2), int32(1)), protocol.LengthOutOfRange},
+		{"slice longer than data", SliceType, raw(uint16(100), uint8(Int64Type)), protocol.LengthOutOfRange},
+		{"dictionary longer than data", DictionaryType, raw(uint8(Int8Type), uint8(Int8Type), uint16(100)), protocol.LengthOutOfRange},
+		{
+			"dictionary with slice key", Hashtable,
+			raw(uint16(1), uint8(Int8SliceType), uint32(1), int8(1), uint8(NilType)),
+			protocol.MalformedData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeType(bytes.NewBuffer(tt.data), tt.typeCode)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := decodeType(bytes.NewBuffer(nil), 255); err == nil {
+		t.Error("unknown type code was decoded")
+	}
+}
@@ -0,0 +1,91 @@
+package photon
+
+import (
+	"M00DSWINGS/protocol"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Deserializer Decodes the reliable message held by a command together with its parameters.
+// Every Photon protocol version brings its own implementation.
+type Deserializer interface {
+	Deserialize(c Command) (ReliableMessage, ReliableMessageParamaters, error)
+}
+
+// NewDeserializer Returns the deserializer for a protocol name as accepted on the command line: 16, 18 or auto
+func NewDeserializer(name string) (Deserializer, error) {
+	switch name {
+	case "16":
+		return Protocol16{}, nil
+	case "18":
+		return Protocol18{}, nil
+	case "auto", "":
+		return NewAutoDeserializer(Protocol16{}, Protocol18{}), nil
+	default:
+		return nil, fmt.Errorf("unknown photon protocol %q", name)
+	}
+}
+
+// Protocol16 Deserializes messages of the original Photon protocol
+type Protocol16 struct{}
+
+func (Protocol16) Deserialize(c Command) (ReliableMessage, ReliableMessageParamaters, error) {
+	msg, err := c.ReliableMessage()
+	if err != nil {
+		return msg, nil, err
+	}
+
+	params, err := DecodeReliableMessage(msg)
+	if err != nil {
+		return msg, nil, err
+	}
+
+	return msg, params, nil
+}
+
+// AutoDeserializer Sticks to the protocol that decoded the last message and switches over
+// to another candidate as soon as one of them can decode a message the current one can't.
+type AutoDeserializer struct {
+	candidates []Deserializer
+	current    int
+	mx         *sync.Mutex
+}
+
+func NewAutoDeserializer(candidates ...Deserializer) *AutoDeserializer {
+	return &AutoDeserializer{
+		candidates: candidates,
+		mx:         new(sync.Mutex),
+	}
+}
+
+// Current Returns the protocol the deserializer settled on
+func (a *AutoDeserializer) Current() Deserializer {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	return a.candidates[a.current]
+}
+
+func (a *AutoDeserializer) Deserialize(c Command) (ReliableMessage, ReliableMessageParamaters, error) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	msg, params, err := a.candidates[a.current].Deserialize(c)
+	if err == nil || errors.Is(err, protocol.EncryptionNotSupported) {
+		return msg, params, err
+	}
+
+	for i, candidate := range a.candidates {
+		if i == a.current {
+			continue
+		}
+
+		if otherMsg, otherParams, otherErr := candidate.Deserialize(c); otherErr == nil {
+			a.current = i
+			return otherMsg, otherParams, nil
+		}
+	}
+
+	return msg, params, err
+}
@@ -0,0 +1,133 @@
+package photon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	// DefaultFragmentSize Payload size the Photon client uses for each fragment
+	DefaultFragmentSize = 1200
+
+	layerHeaderLength = 12
+)
+
+// NewReliableCommand Wraps the data of an encoded message into a SendReliable command
+func NewReliableCommand(channel uint8, sequence uint32, data []byte) Command {
+	return Command{
+		Type:                   SendReliableType,
+		ChannelID:              channel,
+		Flags:                  1,
+		ReservedByte:           4,
+		Length:                 uint32(CommandHeaderLength + len(data)),
+		ReliableSequenceNumber: sequence,
+		Data:                   data,
+	}
+}
+
+// NewUnreliableCommand Wraps the data of an encoded message into a SendUnreliable command
+func NewUnreliableCommand(channel uint8, sequence uint32, unreliableSequence uint32, data []byte) Command {
+	body := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(body, unreliableSequence)
+	body = append(body, data...)
+
+	return Command{
+		Type:                   SendUnreliableType,
+		ChannelID:              channel,
+		ReservedByte:           4,
+		Length:                 uint32(CommandHeaderLength + len(body)),
+		ReliableSequenceNumber: sequence,
+		Data:                   body,
+	}
+}
+
+// NewFragmentCommands Splits the data of an encoded message into SendReliableFragment commands carrying at most
+// fragmentSize bytes each. The fragments use consecutive sequence numbers starting at sequence.
+func NewFragmentCommands(channel uint8, sequence uint32, data []byte, fragmentSize int) []Command {
+	if fragmentSize <= 0 {
+		fragmentSize = DefaultFragmentSize
+	}
+
+	count := (len(data) + fragmentSize - 1) / fragmentSize
+	if count == 0 {
+		count = 1
+	}
+
+	commands := make([]Command, 0, count)
+
+	for i := 0; i < count; i++ {
+		offset := i * fragmentSize
+		end := min(offset+fragmentSize, len(data))
+
+		body := new(bytes.Buffer)
+		_ = binary.Write(body, binary.BigEndian, sequence)
+		_ = binary.Write(body, binary.BigEndian, int32(count))
+		_ = binary.Write(body, binary.BigEndian, int32(i))
+		_ = binary.Write(body, binary.BigEndian, int32(len(data)))
+		_ = binary.Write(body, binary.BigEndian, int32(offset))
+		body.Write(data[offset:end])
+
+		commands = append(commands, Command{
+			Type:                   SendReliableFragmentType,
+			ChannelID:              channel,
+			Flags:                  1,
+			ReservedByte:           4,
+			Length:                 uint32(CommandHeaderLength + body.Len()),
+			ReliableSequenceNumber: sequence + uint32(i),
+			Data:                   body.Bytes(),
+		})
+	}
+
+	return commands
+}
+
+// NewReliableCommands Returns a single SendReliable command, or fragments when the data is larger than fragmentSize
+func NewReliableCommands(channel uint8, sequence uint32, data []byte, fragmentSize int) []Command {
+	if fragmentSize <= 0 {
+		fragmentSize = DefaultFragmentSize
+	}
+
+	if len(data) <= fragmentSize {
+		return []Command{NewReliableCommand(channel, sequence, data)}
+	}
+
+	return NewFragmentCommands(channel, sequence, data, fragmentSize)
+}
+
+// Encode Serializes the command header followed by its data, the length is taken from the data
+func (c Command) Encode() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, CommandHeaderLength+len(c.Data)))
+
+	buf.WriteByte(c.Type)
+	buf.WriteByte(c.ChannelID)
+	buf.WriteByte(c.Flags)
+	buf.WriteByte(c.ReservedByte)
+	_ = binary.Write(buf, binary.BigEndian, uint32(CommandHeaderLength+len(c.Data)))
+	_ = binary.Write(buf, binary.BigEndian, c.ReliableSequenceNumber)
+	buf.Write(c.Data)
+
+	return buf.Bytes()
+}
+
+// Encode Serializes the layer header and its commands into a datagram, the command count is taken from Commands
+func (p Layer) Encode() ([]byte, error) {
+	if len(p.Commands) > math.MaxUint8 {
+		return nil, fmt.Errorf("too many commands for one datagram: %d", len(p.Commands))
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, layerHeaderLength))
+
+	_ = binary.Write(buf, binary.BigEndian, p.PeerID)
+	buf.WriteByte(p.CrcEnabled)
+	buf.WriteByte(uint8(len(p.Commands)))
+	_ = binary.Write(buf, binary.BigEndian, p.Timestamp)
+	_ = binary.Write(buf, binary.BigEndian, p.Challenge)
+
+	for _, command := range p.Commands {
+		buf.Write(command.Encode())
+	}
+
+	return buf.Bytes(), nil
+}
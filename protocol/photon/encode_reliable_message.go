@@ -0,0 +1,341 @@
+package photon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// EncodeReliableMessage Serializes a message header and its parameters with Protocol16, the result
+// is the data of a SendReliable command. ParamaterCount and Data of msg are ignored.
+func EncodeReliableMessage(msg ReliableMessage, params ReliableMessageParamaters) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	signature := msg.Signature
+	if signature == 0 {
+		signature = 0xf3
+	}
+
+	buf.WriteByte(signature)
+	buf.WriteByte(msg.Type)
+
+	switch msg.Type {
+	case OperationRequest:
+		buf.WriteByte(msg.OperationCode)
+	case EventDataType:
+		buf.WriteByte(msg.EventCode)
+	case OperationResponse, otherOperationResponse:
+		buf.WriteByte(msg.OperationCode)
+		_ = binary.Write(buf, binary.BigEndian, msg.OperationResponseCode)
+
+		var debug interface{}
+		if msg.OperationDebugString != "" {
+			debug = msg.OperationDebugString
+		}
+
+		if err := encodeType(buf, debug); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("message type %d can't be encoded", msg.Type)
+	}
+
+	if err := encodeParameters(buf, params); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeParameters Writes the parameter count followed by the parameters ordered by their key
+func encodeParameters(buf *bytes.Buffer, params ReliableMessageParamaters) error {
+	if len(params) > math.MaxUint16 {
+		return fmt.Errorf("too many parameters: %d", len(params))
+	}
+
+	keys := make([]int, 0, len(params))
+	for key := range params {
+		keys = append(keys, int(key))
+	}
+	sort.Ints(keys)
+
+	_ = binary.Write(buf, binary.BigEndian, uint16(len(keys)))
+
+	for _, key := range keys {
+		buf.WriteByte(uint8(key))
+
+		if err := encodeType(buf, params[uint8(key)]); err != nil {
+			return fmt.Errorf("parameter %d: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// encodeType Writes the type code followed by the value, Go types map to the same type codes decodeType turns into them.
+// Plain ints are written as Int32Type.
+func encodeType(buf *bytes.Buffer, value interface{}) error {
+	if value == nil {
+		buf.WriteByte(NilType)
+		return nil
+	}
+
+	typeCode, err := typeCodeOf(value)
+	if err != nil {
+		return err
+	}
+
+	buf.WriteByte(typeCode)
+
+	return encodeValue(buf, value)
+}
+
+func typeCodeOf(value interface{}) (uint8, error) {
+	switch value.(type) {
+	case int8:
+		return Int8Type, nil
+	case int16:
+		return Int16Type, nil
+	case int32, int:
+		return Int32Type, nil
+	case int64:
+		return Int64Type, nil
+	case float32:
+		return Float32Type, nil
+	case float64:
+		return DoubleType, nil
+	case bool:
+		return BooleanType, nil
+	case string:
+		return StringType, nil
+	case []int8, []byte:
+		return Int8SliceType, nil
+	case []int32:
+		return Int32SliceType, nil
+	case []interface{}:
+		return ObjectSliceType, nil
+	case []int16, []int64, []float32, []float64, []bool, []string, [][]int8, []CustomData, []map[interface{}]interface{}:
+		return SliceType, nil
+	case map[interface{}]interface{}:
+		return DictionaryType, nil
+	case CustomData:
+		return Custom, nil
+	case EventData:
+		return EventDateType, nil
+	case OperationRequestData:
+		return OperationRequestType, nil
+	case OperationResponseData:
+		return OperationResponseType, nil
+	default:
+		return 0, fmt.Errorf("type %T can't be encoded", value)
+	}
+}
+
+// encodeValue Writes a value without its type code
+func encodeValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case int8, int16, int32, int64, float32, float64:
+		_ = binary.Write(buf, binary.BigEndian, v)
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return fmt.Errorf("int %d overflows Int32Type", v)
+		}
+		_ = binary.Write(buf, binary.BigEndian, int32(v))
+	case bool:
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case string:
+		return encodeString(buf, v)
+	case []int8:
+		_ = binary.Write(buf, binary.BigEndian, uint32(len(v)))
+		_ = binary.Write(buf, binary.BigEndian, v)
+	case []byte:
+		_ = binary.Write(buf, binary.BigEndian, uint32(len(v)))
+		buf.Write(v)
+	case []int32:
+		_ = binary.Write(buf, binary.BigEndian, uint32(len(v)))
+		_ = binary.Write(buf, binary.BigEndian, v)
+	case []interface{}:
+		if err := encodeLength(buf, len(v)); err != nil {
+			return err
+		}
+
+		for _, item := range v {
+			if err := encodeType(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		// Key and value types are written per entry
+		buf.WriteByte(0)
+		buf.WriteByte(0)
+
+		return encodeDictionaryElements(buf, v)
+	case CustomData:
+		buf.WriteByte(v.TypeCode)
+		return encodeCustomData(buf, v.Data)
+	case EventData:
+		buf.WriteByte(v.Code)
+		return encodeParameters(buf, v.Parameters)
+	case OperationRequestData:
+		buf.WriteByte(v.OperationCode)
+		return encodeParameters(buf, v.Parameters)
+	case OperationResponseData:
+		buf.WriteByte(v.OperationCode)
+		_ = binary.Write(buf, binary.BigEndian, v.OperationResponseCode)
+
+		var debug interface{}
+		if v.OperationDebugString != "" {
+			debug = v.OperationDebugString
+		}
+
+		if err := encodeType(buf, debug); err != nil {
+			return err
+		}
+
+		return encodeParameters(buf, v.Parameters)
+	default:
+		return encodeSlice(buf, value)
+	}
+
+	return nil
+}
+
+// encodeSlice Writes a SliceType, all elements share the type code written once up front
+func encodeSlice(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case []int16:
+		return encodeSliceElements(buf, Int16Type, v)
+	case []int64:
+		return encodeSliceElements(buf, Int64Type, v)
+	case []float32:
+		return encodeSliceElements(buf, Float32Type, v)
+	case []float64:
+		return encodeSliceElements(buf, DoubleType, v)
+	case []bool:
+		return encodeSliceElements(buf, BooleanType, v)
+	case []string:
+		return encodeSliceElements(buf, StringType, v)
+	case [][]int8:
+		return encodeSliceElements(buf, Int8SliceType, v)
+	case []map[interface{}]interface{}:
+		if err := encodeLength(buf, len(v)); err != nil {
+			return err
+		}
+
+		buf.WriteByte(DictionaryType)
+		buf.WriteByte(0)
+		buf.WriteByte(0)
+
+		for _, item := range v {
+			if err := encodeDictionaryElements(buf, item); err != nil {
+				return err
+			}
+		}
+	case []CustomData:
+		if err := encodeLength(buf, len(v)); err != nil {
+			return err
+		}
+
+		buf.WriteByte(Custom)
+
+		var typeCode uint8
+		if len(v) > 0 {
+			typeCode = v[0].TypeCode
+		}
+		buf.WriteByte(typeCode)
+
+		for _, item := range v {
+			if item.TypeCode != typeCode {
+				return fmt.Errorf("custom slice mixes type codes %d and %d", typeCode, item.TypeCode)
+			}
+
+			if err := encodeCustomData(buf, item.Data); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("type %T can't be encoded", value)
+	}
+
+	return nil
+}
+
+func encodeSliceElements[T any](buf *bytes.Buffer, typeCode uint8, values []T) error {
+	if err := encodeLength(buf, len(values)); err != nil {
+		return err
+	}
+
+	buf.WriteByte(typeCode)
+
+	for _, value := range values {
+		if err := encodeValue(buf, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeDictionaryElements(buf *bytes.Buffer, dictionary map[interface{}]interface{}) error {
+	if err := encodeLength(buf, len(dictionary)); err != nil {
+		return err
+	}
+
+	// Sort the keys so the same dictionary always encodes to the same bytes
+	keys := make([]interface{}, 0, len(dictionary))
+	for key := range dictionary {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprintf("%T%v", keys[i], keys[i]) < fmt.Sprintf("%T%v", keys[j], keys[j])
+	})
+
+	for _, key := range keys {
+		if err := encodeType(buf, key); err != nil {
+			return err
+		}
+
+		if err := encodeType(buf, dictionary[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeString(buf *bytes.Buffer, value string) error {
+	if err := encodeLength(buf, len(value)); err != nil {
+		return err
+	}
+
+	buf.WriteString(value)
+
+	return nil
+}
+
+func encodeCustomData(buf *bytes.Buffer, data []byte) error {
+	if err := encodeLength(buf, len(data)); err != nil {
+		return err
+	}
+
+	buf.Write(data)
+
+	return nil
+}
+
+func encodeLength(buf *bytes.Buffer, length int) error {
+	if length > math.MaxUint16 {
+		return fmt.Errorf("length %d doesn't fit into 16 bits", length)
+	}
+
+	_ = binary.Write(buf, binary.BigEndian, uint16(length))
+
+	return nil
+}
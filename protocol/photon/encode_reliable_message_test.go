@@ -0,0 +1,145 @@
+package photon
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+)
+
+func TestEncodeTypeRoundTrip(t *testing.T) {
+	for _, tt := range typeTests() {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := encodeType(buf, tt.want); err != nil {
+				t.Fatal(err)
+			}
+
+			typeCode, _ := buf.ReadByte()
+
+			got, err := decodeType(buf, typeCode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+
+			if buf.Len() != 0 {
+				t.Errorf("%d bytes left unread", buf.Len())
+			}
+		})
+	}
+}
+
+func TestEncodeReliableMessageRoundTrip(t *testing.T) {
+	params := ReliableMessageParamaters{
+		0:   int16(14368),
+		1:   "Triky313",
+		4:   [][]int8{{1, 2}, {3}},
+		5:   []string{"Triky313", "Bruno313"},
+		252: int16(229),
+	}
+
+	messages := []ReliableMessage{
+		{Type: EventDataType, EventCode: 1},
+		{Type: OperationRequest, OperationCode: 1},
+		{Type: OperationResponse, OperationCode: 1, OperationResponseCode: 3, OperationDebugString: "oops"},
+	}
+
+	for _, want := range messages {
+		data, err := EncodeReliableMessage(want, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, got, err := Protocol16{}.Deserialize(NewReliableCommand(0, 1, data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.Type != want.Type || msg.EventCode != want.EventCode || msg.OperationCode != want.OperationCode ||
+			msg.OperationResponseCode != want.OperationResponseCode || msg.OperationDebugString != want.OperationDebugString {
+			t.Errorf("got header %+v, want %+v", msg, want)
+		}
+
+		if !reflect.DeepEqual(got, params) {
+			t.Errorf("got %#v, want %#v", got, params)
+		}
+	}
+}
+
+func TestEncodeFragmentedDatagrams(t *testing.T) {
+	names := make([]string, 300)
+	for i := range names {
+		names[i] = "PLAYER_NAME"
+	}
+
+	params := ReliableMessageParamaters{1: names, 252: int16(229)}
+
+	data, err := EncodeReliableMessage(ReliableMessage{Type: EventDataType, EventCode: 1}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := NewReliableCommands(0, 100, data, DefaultFragmentSize)
+	if len(commands) < 2 {
+		t.Fatalf("%d bytes were not fragmented", len(data))
+	}
+
+	fragments := NewFragmentBuffer()
+
+	var assembled *Command
+
+	// One datagram per fragment, like the game server sends them
+	for i, command := range commands {
+		datagram, err := Layer{PeerID: 1, Commands: []Command{command}}.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		packet := gopacket.NewPacket(datagram, LayerType, gopacket.DecodeOptions{SkipDecodeRecovery: true})
+
+		layer, ok := packet.Layer(LayerType).(Layer)
+		if !ok || len(layer.Commands) != 1 {
+			t.Fatalf("datagram %d did not decode: %v", i, packet.ErrorLayer())
+		}
+
+		fragment, err := layer.Commands[0].ReliableFragment()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assembled = fragments.Offer(fragment)
+	}
+
+	if assembled == nil {
+		t.Fatal("fragments were not assembled")
+	}
+
+	_, got, err := Protocol16{}.Deserialize(*assembled)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, params) {
+		t.Errorf("got %#v, want %#v", got, params)
+	}
+}
+
+func TestNewReliableCommandsBoundary(t *testing.T) {
+	if commands := NewReliableCommands(0, 1, make([]byte, 64), 64); len(commands) != 1 || commands[0].Type != SendReliableType {
+		t.Errorf("data of fragmentSize was sent as %d commands", len(commands))
+	}
+
+	commands := NewReliableCommands(0, 1, make([]byte, 65), 64)
+	if len(commands) != 2 || commands[0].Type != SendReliableFragmentType {
+		t.Fatalf("data over fragmentSize was sent as %d commands", len(commands))
+	}
+
+	if commands[1].ReliableSequenceNumber != 2 {
+		t.Errorf("second fragment has sequence %d, want 2", commands[1].ReliableSequenceNumber)
+	}
+}
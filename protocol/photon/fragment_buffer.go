@@ -4,6 +4,9 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 )
 
+// maxFragmentedLength Caps the total length a first fragment may announce, the buffer is allocated up front
+const maxFragmentedLength = 1 << 20
+
 // FragmentBuffer Provides an LRU backed buffer which will assemble ReliableFragments
 // into a single PhotonCommand with type ReliableMessage
 type FragmentBuffer struct {
@@ -11,52 +14,76 @@ type FragmentBuffer struct {
 }
 
 // Offer Offers a message to the buffer. Returns nil when no new commands could be assembled from the
-// buffer's contents.
+// buffer's contents. Fragments that disagree with the first fragment of their sequence are dropped.
 func (buf *FragmentBuffer) Offer(msg ReliableFragment) *Command {
-	var entry fragmentBufferEntry
-
-	if buf.cache.Contains(msg.SequenceNumber) {
-		obj, _ := buf.cache.Get(msg.SequenceNumber)
-		entry = obj.(fragmentBufferEntry)
-		entry.Fragments[int(msg.FragmentNumber)] = msg.Data
+	var entry *fragmentBufferEntry
 
+	if obj, ok := buf.cache.Get(msg.SequenceNumber); ok {
+		entry = obj.(*fragmentBufferEntry)
 	} else {
-		entry.SequenceNumber = msg.SequenceNumber
-		entry.FragmentsNeeded = int(msg.FragmentCount)
-		entry.Fragments = make(map[int][]byte)
-		entry.Fragments[int(msg.FragmentNumber)] = msg.Data
-	}
+		if msg.TotalLength <= 0 || msg.TotalLength > maxFragmentedLength {
+			return nil
+		}
 
-	if entry.Finished() {
-		command := entry.Make()
-		buf.cache.Remove(msg.SequenceNumber)
-		return &command
-	} else {
+		entry = &fragmentBufferEntry{
+			SequenceNumber: msg.SequenceNumber,
+			FragmentCount:  msg.FragmentCount,
+			TotalLength:    msg.TotalLength,
+			Data:           make([]byte, msg.TotalLength),
+			Received:       make(map[int32]bool),
+		}
 		buf.cache.Add(msg.SequenceNumber, entry)
+	}
+
+	if !entry.Accepts(msg) {
 		return nil
 	}
-}
 
-type fragmentBufferEntry struct {
-	SequenceNumber  uint32
-	FragmentsNeeded int
-	Fragments       map[int][]byte
+	copy(entry.Data[msg.FragmentOffset:], msg.Data)
+	entry.Received[msg.FragmentNumber] = true
+	entry.Written += len(msg.Data)
+
+	if len(entry.Received) < int(entry.FragmentCount) {
+		return nil
+	}
+
+	buf.cache.Remove(msg.SequenceNumber)
+
+	// Every fragment arrived but they don't cover the message, so something overlapped or was left out
+	if entry.Written != int(entry.TotalLength) {
+		return nil
+	}
+
+	command := entry.Make()
+	return &command
 }
 
-func (buf fragmentBufferEntry) Finished() bool {
-	return len(buf.Fragments) == buf.FragmentsNeeded
+type fragmentBufferEntry struct {
+	SequenceNumber uint32
+	FragmentCount  int32
+	TotalLength    int32
+	Data           []byte
+	Received       map[int32]bool
+	Written        int
 }
 
-func (buf fragmentBufferEntry) Make() Command {
-	var data []byte
+// Accepts Checks a fragment against the first fragment of the sequence and the room left in the message
+func (buf *fragmentBufferEntry) Accepts(msg ReliableFragment) bool {
+	if msg.FragmentCount != buf.FragmentCount || msg.TotalLength != buf.TotalLength {
+		return false
+	}
 
-	for i := 0; i < buf.FragmentsNeeded; i++ {
-		data = append(data, buf.Fragments[i]...)
+	if msg.FragmentNumber < 0 || msg.FragmentNumber >= buf.FragmentCount || buf.Received[msg.FragmentNumber] {
+		return false
 	}
 
+	return msg.FragmentOffset >= 0 && int64(msg.FragmentOffset)+int64(len(msg.Data)) <= int64(buf.TotalLength)
+}
+
+func (buf *fragmentBufferEntry) Make() Command {
 	return Command{
 		Type:                   SendReliableType,
-		Data:                   data,
+		Data:                   buf.Data,
 		ReliableSequenceNumber: buf.SequenceNumber,
 	}
 }
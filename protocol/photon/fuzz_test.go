@@ -0,0 +1,191 @@
+package photon
+
+import (
+	"bytes"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/gopacket"
+)
+
+// addCorpusSeeds Seeds a fuzz target with every datagram of the golden corpus.
+func addCorpusSeeds(f *testing.F, seed func(data []byte)) {
+	cases, err := filepath.Glob(filepath.Join("testdata", "*.hex"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, path := range cases {
+		for _, data := range readCorpus(f, path) {
+			seed(data)
+		}
+	}
+}
+
+// corpusCommands Returns the commands of a corpus datagram, nil when it is not a valid packet.
+func corpusCommands(data []byte) []Command {
+	packet := gopacket.NewPacket(data, LayerType, gopacket.Default)
+
+	if layer, ok := packet.Layer(LayerType).(Layer); ok {
+		return layer.Commands
+	}
+
+	return nil
+}
+
+func FuzzDecodePhotonPacket(f *testing.F) {
+	addCorpusSeeds(f, func(data []byte) {
+		f.Add(data)
+	})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		packet := gopacket.NewPacket(data, LayerType, gopacket.DecodeOptions{SkipDecodeRecovery: true})
+
+		layer, ok := packet.Layer(LayerType).(Layer)
+		if !ok {
+			return
+		}
+
+		for _, command := range layer.Commands {
+			if int(command.Length)-CommandHeaderLength != len(command.Data) {
+				t.Fatalf("command length %d doesn't match %d bytes of data", command.Length, len(command.Data))
+			}
+		}
+	})
+}
+
+func FuzzDecodeReliableMessage(f *testing.F) {
+	addCorpusSeeds(f, func(data []byte) {
+		for _, command := range corpusCommands(data) {
+			if command.Type == SendReliableType {
+				f.Add(command.Data)
+			}
+		}
+	})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := Command{Type: SendReliableType, Data: data}.ReliableMessage()
+		if err != nil {
+			return
+		}
+
+		_, _ = DecodeReliableMessage(msg)
+	})
+}
+
+func FuzzReliableFragment(f *testing.F) {
+	addCorpusSeeds(f, func(data []byte) {
+		for _, command := range corpusCommands(data) {
+			if command.Type == SendReliableFragmentType {
+				f.Add(command.Data)
+			}
+		}
+	})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fragment, err := Command{Type: SendReliableFragmentType, Data: data}.ReliableFragment()
+		if err != nil {
+			return
+		}
+
+		// Whatever a lone fragment claims, an assembled command never outgrows its announced length
+		result := NewFragmentBuffer().Offer(fragment)
+		if result == nil {
+			return
+		}
+
+		if len(result.Data) != int(fragment.TotalLength) {
+			t.Fatalf("assembled %d bytes, fragment announced %d", len(result.Data), fragment.TotalLength)
+		}
+
+		if msg, err := result.ReliableMessage(); err == nil {
+			_, _ = DecodeReliableMessage(msg)
+		}
+	})
+}
+
+// FuzzFragmentSequence Splits a message into fragments, offers them in a shuffled order with one of them
+// optionally tampered with, and checks the buffer assembles the message or nothing at all.
+func FuzzFragmentSequence(f *testing.F) {
+	f.Add([]byte("a reliable message split into several fragments"), uint8(3), int64(1), uint8(0))
+	f.Add([]byte("tampered"), uint8(4), int64(7), uint8(1))
+	f.Add([]byte("overlap"), uint8(2), int64(3), uint8(2))
+
+	f.Fuzz(func(t *testing.T, payload []byte, count uint8, seed int64, tamper uint8) {
+		if len(payload) == 0 || count == 0 {
+			return
+		}
+
+		fragments := splitFragments(payload, min(int(count), len(payload)))
+		random := rand.New(rand.NewSource(seed))
+		random.Shuffle(len(fragments), func(i, j int) {
+			fragments[i], fragments[j] = fragments[j], fragments[i]
+		})
+
+		victim := &fragments[random.Intn(len(fragments))]
+		switch tamper % 4 {
+		case 1:
+			victim.TotalLength++
+		case 2:
+			victim.FragmentOffset = victim.TotalLength
+		case 3:
+			victim.FragmentCount++
+		}
+
+		buffer := NewFragmentBuffer()
+		var assembled *Command
+		for i, fragment := range fragments {
+			result := buffer.Offer(fragment)
+			if result != nil && (assembled != nil || i != len(fragments)-1) {
+				t.Fatalf("assembled at fragment %d of %d", i+1, len(fragments))
+			}
+			assembled = result
+		}
+
+		if tamper%4 == 0 && (assembled == nil || !bytes.Equal(assembled.Data, payload)) {
+			t.Fatal("untouched fragments were not assembled into the message")
+		}
+
+		// A tampered first fragment sets the rules, so the others are dropped instead
+		if tamper%4 != 0 && assembled != nil && !bytes.Equal(assembled.Data, payload) {
+			t.Fatalf("tampered fragments assembled into %x", assembled.Data)
+		}
+	})
+}
+
+// splitFragments Cuts a message into count fragments of one sequence with their offsets filled in
+func splitFragments(payload []byte, count int) []ReliableFragment {
+	fragments := make([]ReliableFragment, count)
+	size := (len(payload) + count - 1) / count
+
+	for i := range fragments {
+		start := min(i*size, len(payload))
+		end := min(start+size, len(payload))
+		fragments[i] = ReliableFragment{
+			SequenceNumber: 1,
+			FragmentCount:  int32(count),
+			FragmentNumber: int32(i),
+			TotalLength:    int32(len(payload)),
+			FragmentOffset: int32(start),
+			Data:           payload[start:end],
+		}
+	}
+
+	return fragments
+}
+
+func FuzzProtocol18(f *testing.F) {
+	addCorpusSeeds(f, func(data []byte) {
+		for _, command := range corpusCommands(data) {
+			if command.Type == SendReliableType {
+				f.Add(command.Data)
+			}
+		}
+	})
+	f.Add(nestedZeroArrays())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _, _ = Protocol18{}.Deserialize(Command{Type: SendReliableType, Data: data})
+	})
+}
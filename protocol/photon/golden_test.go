@@ -0,0 +1,275 @@
+package photon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden files in testdata")
+
+// goldenEntry Describes a single command found in a corpus case. Reassembled fragments
+// show up as an extra SendReliable entry right after the fragment that completed them.
+type goldenEntry struct {
+	Datagram              int                   `json:"datagram"`
+	CommandType           uint8                 `json:"commandType"`
+	Fragment              *goldenFragment       `json:"fragment,omitempty"`
+	MessageType           uint8                 `json:"messageType,omitempty"`
+	OperationCode         uint8                 `json:"operationCode,omitempty"`
+	EventCode             uint8                 `json:"eventCode,omitempty"`
+	OperationResponseCode uint16                `json:"operationResponseCode,omitempty"`
+	OperationDebugString  string                `json:"operationDebugString,omitempty"`
+	Parameters            map[uint8]goldenValue `json:"parameters,omitempty"`
+	Error                 string                `json:"error,omitempty"`
+}
+
+type goldenFragment struct {
+	SequenceNumber uint32 `json:"sequenceNumber"`
+	FragmentCount  int32  `json:"fragmentCount"`
+	FragmentNumber int32  `json:"fragmentNumber"`
+}
+
+// goldenValue Keeps the Go type next to the value, so a changed integer width is caught as well.
+type goldenValue struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+type goldenPair struct {
+	Key   goldenValue `json:"key"`
+	Value goldenValue `json:"value"`
+}
+
+func TestGolden(t *testing.T) {
+	cases, err := filepath.Glob(filepath.Join("testdata", "*.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cases) == 0 {
+		t.Fatal("no corpus found in testdata")
+	}
+
+	for _, path := range cases {
+		name := strings.TrimSuffix(filepath.Base(path), ".hex")
+
+		t.Run(name, func(t *testing.T) {
+			datagrams := readCorpus(t, path)
+
+			got, err := json.MarshalIndent(decodeCorpus(datagrams), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", name+".golden.json")
+
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file, run go test -update: %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("decoded output differs from %s, run go test -update if this is expected\n--- got\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// readCorpus Reads one hex encoded datagram per line, lines starting with # are comments.
+func readCorpus(t testing.TB, path string) [][]byte {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	var datagrams [][]byte
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		data, err := hex.DecodeString(line)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+
+		datagrams = append(datagrams, data)
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return datagrams
+}
+
+// decodeCorpus Runs the datagrams through the same steps the Logger takes for every command.
+func decodeCorpus(datagrams [][]byte) []goldenEntry {
+	entries := make([]goldenEntry, 0)
+	fragments := NewFragmentBuffer()
+
+	for i, data := range datagrams {
+		packet := gopacket.NewPacket(data, LayerType, gopacket.Default)
+
+		layer, ok := packet.Layer(LayerType).(Layer)
+		if !ok {
+			entry := goldenEntry{Datagram: i, Error: "not a photon packet"}
+			if failure := packet.ErrorLayer(); failure != nil {
+				entry.Error = failure.Error().Error()
+			}
+
+			entries = append(entries, entry)
+			continue
+		}
+
+		for _, command := range layer.Commands {
+			entry := goldenEntry{Datagram: i, CommandType: command.Type}
+
+			switch command.Type {
+			case SendReliableType:
+				decodeMessage(&entry, command)
+			case SendUnreliableType:
+				if len(command.Data) < 4 {
+					entry.Error = "unreliable command too short"
+					break
+				}
+
+				command.Data = command.Data[4:]
+				command.Type = SendReliableType
+				decodeMessage(&entry, command)
+			case SendReliableFragmentType:
+				fragment, err := command.ReliableFragment()
+				if err != nil {
+					entry.Error = err.Error()
+					break
+				}
+
+				entry.Fragment = &goldenFragment{
+					SequenceNumber: fragment.SequenceNumber,
+					FragmentCount:  fragment.FragmentCount,
+					FragmentNumber: fragment.FragmentNumber,
+				}
+
+				if result := fragments.Offer(fragment); result != nil {
+					entries = append(entries, entry)
+
+					entry = goldenEntry{Datagram: i, CommandType: result.Type}
+					decodeMessage(&entry, *result)
+				}
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+func decodeMessage(entry *goldenEntry, command Command) {
+	msg, err := command.ReliableMessage()
+	if err != nil {
+		entry.Error = err.Error()
+		return
+	}
+
+	entry.MessageType = msg.Type
+	entry.OperationCode = msg.OperationCode
+	entry.EventCode = msg.EventCode
+	entry.OperationResponseCode = msg.OperationResponseCode
+	entry.OperationDebugString = msg.OperationDebugString
+
+	params, err := DecodeReliableMessage(msg)
+	if err != nil {
+		entry.Error = err.Error()
+		return
+	}
+
+	entry.Parameters = newGoldenParameters(params)
+}
+
+func newGoldenParameters(params ReliableMessageParamaters) map[uint8]goldenValue {
+	result := make(map[uint8]goldenValue, len(params))
+	for key, value := range params {
+		result[key] = newGoldenValue(value)
+	}
+
+	return result
+}
+
+func newGoldenValue(value any) goldenValue {
+	result := goldenValue{Type: fmt.Sprintf("%T", value), Value: value}
+
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]goldenValue, len(v))
+		for i, item := range v {
+			values[i] = newGoldenValue(item)
+		}
+
+		result.Value = values
+	case map[interface{}]interface{}:
+		pairs := make([]goldenPair, 0, len(v))
+		for key, item := range v {
+			pairs = append(pairs, goldenPair{Key: newGoldenValue(key), Value: newGoldenValue(item)})
+		}
+
+		sort.Slice(pairs, func(i, j int) bool {
+			return fmt.Sprint(pairs[i].Key.Value) < fmt.Sprint(pairs[j].Key.Value)
+		})
+
+		result.Value = pairs
+	case []map[interface{}]interface{}:
+		values := make([]goldenValue, len(v))
+		for i, item := range v {
+			values[i] = newGoldenValue(item)
+		}
+
+		result.Value = values
+	case EventData:
+		result.Value = map[string]any{
+			"code":       v.Code,
+			"parameters": newGoldenParameters(v.Parameters),
+		}
+	case OperationRequestData:
+		result.Value = map[string]any{
+			"operationCode": v.OperationCode,
+			"parameters":    newGoldenParameters(v.Parameters),
+		}
+	case OperationResponseData:
+		result.Value = map[string]any{
+			"operationCode":         v.OperationCode,
+			"operationResponseCode": v.OperationResponseCode,
+			"operationDebugString":  v.OperationDebugString,
+			"parameters":            newGoldenParameters(v.Parameters),
+		}
+	}
+
+	return result
+}
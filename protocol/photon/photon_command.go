@@ -114,11 +114,9 @@ func (c Command) ReliableMessage() (msg ReliableMessage, err error) {
 			return msg, err
 		}
 
-		if paramValue == nil {
-			paramValue = ""
+		if debugString, ok := paramValue.(string); ok {
+			msg.OperationDebugString = debugString
 		}
-
-		msg.OperationDebugString = paramValue.(string)
 	}
 
 	if err := binary.Read(buf, binary.BigEndian, &msg.ParamaterCount); err != nil {
@@ -157,6 +155,10 @@ func (c Command) ReliableFragment() (msg ReliableFragment, err error) {
 		return ReliableFragment{}, err
 	}
 
+	if msg.FragmentCount <= 0 || msg.FragmentNumber < 0 || msg.FragmentNumber >= msg.FragmentCount {
+		return ReliableFragment{}, fmt.Errorf("%w: fragment %d of %d", protocol.MalformedData, msg.FragmentNumber, msg.FragmentCount)
+	}
+
 	msg.Data = buf.Bytes()
 
 	return
@@ -1,8 +1,11 @@
 package photon
 
 import (
+	"M00DSWINGS/protocol"
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"io"
 
 	"github.com/google/gopacket"
 )
@@ -96,12 +99,16 @@ func DecodePhotonPacket(data []byte, p gopacket.PacketBuilder) error {
 		dataLength := int(command.Length) - CommandHeaderLength
 
 		// Ensure we don't try to read more than we have
+		if dataLength < 0 {
+			return fmt.Errorf("%w: command length %d is shorter than its header", protocol.MalformedData, command.Length)
+		}
+
 		if dataLength > buf.Len() {
-			panic("Data is malformed")
+			return fmt.Errorf("%w: command length %d, %d bytes left", protocol.LengthOutOfRange, dataLength, buf.Len())
 		}
 
 		command.Data = make([]byte, dataLength)
-		if _, err := buf.Read(command.Data); err != nil {
+		if _, err := io.ReadFull(buf, command.Data); err != nil {
 			return err
 		}
 
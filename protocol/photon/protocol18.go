@@ -0,0 +1,689 @@
+package photon
+
+import (
+	"M00DSWINGS/protocol"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Protocol18 type codes
+const (
+	Protocol18Unknown             = 0
+	Protocol18Boolean             = 2
+	Protocol18Byte                = 3
+	Protocol18Short               = 4
+	Protocol18Float               = 5
+	Protocol18Double              = 6
+	Protocol18String              = 7
+	Protocol18Null                = 8
+	Protocol18CompressedInt       = 9
+	Protocol18CompressedLong      = 10
+	Protocol18Int1                = 11
+	Protocol18Int1Negative        = 12
+	Protocol18Int2                = 13
+	Protocol18Int2Negative        = 14
+	Protocol18Long1               = 15
+	Protocol18Long1Negative       = 16
+	Protocol18Long2               = 17
+	Protocol18Long2Negative       = 18
+	Protocol18Custom              = 19
+	Protocol18Dictionary          = 20
+	Protocol18Hashtable           = 21
+	Protocol18ObjectArray         = 23
+	Protocol18OperationRequest    = 24
+	Protocol18OperationResponse   = 25
+	Protocol18EventData           = 26
+	Protocol18BooleanFalse        = 27
+	Protocol18BooleanTrue         = 28
+	Protocol18ShortZero           = 29
+	Protocol18IntZero             = 30
+	Protocol18LongZero            = 31
+	Protocol18FloatZero           = 32
+	Protocol18DoubleZero          = 33
+	Protocol18ByteZero            = 34
+	Protocol18Array               = 64
+	Protocol18BooleanArray        = 66
+	Protocol18ByteArray           = 67
+	Protocol18ShortArray          = 68
+	Protocol18FloatArray          = 69
+	Protocol18DoubleArray         = 70
+	Protocol18StringArray         = 71
+	Protocol18CompressedIntArray  = 73
+	Protocol18CompressedLongArray = 74
+	Protocol18CustomTypeArray     = 83
+	Protocol18DictionaryArray     = 84
+	Protocol18HashtableArray      = 85
+	Protocol18CustomTypeSlim      = 128
+)
+
+// maxZeroElementsProtocol18 Bounds how many elements a message may hold beyond its size in bytes.
+// Zero value type codes take no bytes at all, so nested arrays of them could otherwise claim the
+// rest of the buffer at every level.
+const maxZeroElementsProtocol18 = 1 << 16
+
+// protocol18Buffer Keeps track of how many more elements a message may decode across all its
+// nesting levels
+type protocol18Buffer struct {
+	*bytes.Buffer
+	budget uint64
+}
+
+func newProtocol18Buffer(data []byte) *protocol18Buffer {
+	return &protocol18Buffer{Buffer: bytes.NewBuffer(data), budget: uint64(len(data)) + maxZeroElementsProtocol18}
+}
+
+// charge Takes length elements off the budget of the message
+func (buf *protocol18Buffer) charge(length uint64) error {
+	if length > buf.budget {
+		return fmt.Errorf("%w: %d elements, %d left in the message", protocol.LengthOutOfRange, length, buf.budget)
+	}
+
+	buf.budget -= length
+	return nil
+}
+
+// Protocol18 Deserializes messages of Photon 2, which uses little endian numbers, compressed
+// variable length integers and a parameter count of a single byte.
+//
+// Values are returned as the same Go types the Protocol16 decoder uses, so packets keep working
+// no matter which protocol the game client speaks.
+type Protocol18 struct{}
+
+func (p Protocol18) Deserialize(c Command) (ReliableMessage, ReliableMessageParamaters, error) {
+	msg, err := p.ReliableMessage(c)
+	if err != nil {
+		return msg, nil, err
+	}
+
+	params, err := p.DecodeReliableMessage(msg)
+	if err != nil {
+		return msg, nil, err
+	}
+
+	return msg, params, nil
+}
+
+func (Protocol18) ReliableMessage(c Command) (msg ReliableMessage, err error) {
+	if c.Type != SendReliableType {
+		return msg, fmt.Errorf("command can't be converted")
+	}
+
+	buf := newProtocol18Buffer(c.Data)
+
+	if err := binary.Read(buf, binary.BigEndian, &msg.Signature); err != nil {
+		return ReliableMessage{}, err
+	}
+
+	if err := binary.Read(buf, binary.BigEndian, &msg.Type); err != nil {
+		return ReliableMessage{}, err
+	}
+
+	if msg.Type > 128 {
+		return msg, protocol.EncryptionNotSupported
+	}
+
+	if msg.Type == otherOperationResponse {
+		msg.Type = OperationResponse
+	}
+
+	switch msg.Type {
+	case OperationRequest:
+		if err := binary.Read(buf, binary.BigEndian, &msg.OperationCode); err != nil {
+			return ReliableMessage{}, err
+		}
+	case EventDataType:
+		if err := binary.Read(buf, binary.BigEndian, &msg.EventCode); err != nil {
+			return ReliableMessage{}, err
+		}
+	case OperationResponse:
+		if err := binary.Read(buf, binary.BigEndian, &msg.OperationCode); err != nil {
+			return ReliableMessage{}, err
+		}
+
+		var returnCode int16
+		if err := binary.Read(buf, binary.LittleEndian, &returnCode); err != nil {
+			return ReliableMessage{}, err
+		}
+		msg.OperationResponseCode = uint16(returnCode)
+
+		debug, err := decodeTypedProtocol18(buf)
+		if err != nil {
+			return msg, err
+		}
+
+		if debugString, ok := debug.(string); ok {
+			msg.OperationDebugString = debugString
+		}
+	}
+
+	var count uint8
+	if err := binary.Read(buf, binary.BigEndian, &count); err != nil {
+		return ReliableMessage{}, err
+	}
+
+	msg.ParamaterCount = uint16(count)
+	msg.Data = buf.Bytes()
+
+	return
+}
+
+func (Protocol18) DecodeReliableMessage(msg ReliableMessage) (ReliableMessageParamaters, error) {
+	buf := newProtocol18Buffer(msg.Data)
+
+	return decodeParametersProtocol18(buf, int(msg.ParamaterCount))
+}
+
+func decodeParametersProtocol18(buf *protocol18Buffer, count int) (ReliableMessageParamaters, error) {
+	params := make(map[uint8]interface{})
+
+	for i := 0; i < count; i++ {
+		var paramID uint8
+		if err := binary.Read(buf, binary.BigEndian, &paramID); err != nil {
+			return nil, err
+		}
+
+		decoded, err := decodeTypedProtocol18(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		params[paramID] = decoded
+	}
+
+	return params, nil
+}
+
+func decodeParameterTableProtocol18(buf *protocol18Buffer) (ReliableMessageParamaters, error) {
+	var count uint8
+	if err := binary.Read(buf, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+
+	return decodeParametersProtocol18(buf, int(count))
+}
+
+// decodeTypedProtocol18 Reads a type code followed by its value
+func decodeTypedProtocol18(buf *protocol18Buffer) (interface{}, error) {
+	var typeCode uint8
+	if err := binary.Read(buf, binary.BigEndian, &typeCode); err != nil {
+		return nil, err
+	}
+
+	return decodeTypeProtocol18(buf, typeCode)
+}
+
+func decodeTypeProtocol18(buf *protocol18Buffer, typeCode uint8) (interface{}, error) {
+	if typeCode >= Protocol18CustomTypeSlim {
+		return decodeCustomProtocol18(buf, typeCode-Protocol18CustomTypeSlim)
+	}
+
+	switch typeCode {
+	case Protocol18Unknown, Protocol18Null:
+		return nil, nil
+	case Protocol18Boolean:
+		return decodeBooleanType(buf.Buffer)
+	case Protocol18BooleanFalse:
+		return false, nil
+	case Protocol18BooleanTrue:
+		return true, nil
+	case Protocol18Byte:
+		return decodeInt8Type(buf.Buffer)
+	case Protocol18ByteZero:
+		return int8(0), nil
+	case Protocol18Short:
+		var value int16
+		err := binary.Read(buf, binary.LittleEndian, &value)
+		return value, err
+	case Protocol18ShortZero:
+		return int16(0), nil
+	case Protocol18Float:
+		var value float32
+		err := binary.Read(buf, binary.LittleEndian, &value)
+		return value, err
+	case Protocol18FloatZero:
+		return float32(0), nil
+	case Protocol18Double:
+		var value float64
+		err := binary.Read(buf, binary.LittleEndian, &value)
+		return value, err
+	case Protocol18DoubleZero:
+		return float64(0), nil
+	case Protocol18String:
+		return decodeStringProtocol18(buf)
+	case Protocol18CompressedInt:
+		value, err := readCompressedUint64(buf)
+		return int32(zigzagDecode(value)), err
+	case Protocol18CompressedLong:
+		value, err := readCompressedUint64(buf)
+		return zigzagDecode(value), err
+	case Protocol18Int1, Protocol18Int1Negative:
+		value, err := buf.ReadByte()
+		if typeCode == Protocol18Int1Negative {
+			return -int32(value), err
+		}
+		return int32(value), err
+	case Protocol18Int2, Protocol18Int2Negative:
+		var value uint16
+		err := binary.Read(buf, binary.LittleEndian, &value)
+		if typeCode == Protocol18Int2Negative {
+			return -int32(value), err
+		}
+		return int32(value), err
+	case Protocol18IntZero:
+		return int32(0), nil
+	case Protocol18Long1, Protocol18Long1Negative:
+		value, err := buf.ReadByte()
+		if typeCode == Protocol18Long1Negative {
+			return -int64(value), err
+		}
+		return int64(value), err
+	case Protocol18Long2, Protocol18Long2Negative:
+		var value uint16
+		err := binary.Read(buf, binary.LittleEndian, &value)
+		if typeCode == Protocol18Long2Negative {
+			return -int64(value), err
+		}
+		return int64(value), err
+	case Protocol18LongZero:
+		return int64(0), nil
+	case Protocol18Custom:
+		var customType uint8
+		if err := binary.Read(buf, binary.BigEndian, &customType); err != nil {
+			return nil, err
+		}
+		return decodeCustomProtocol18(buf, customType)
+	case Protocol18Dictionary:
+		keyTypeCode, valueTypeCode, err := decodeDictionaryTypeCodesProtocol18(buf)
+		if err != nil {
+			return nil, err
+		}
+		return decodeDictionaryProtocol18(buf, keyTypeCode, valueTypeCode)
+	case Protocol18Hashtable:
+		return decodeDictionaryProtocol18(buf, Protocol18Unknown, Protocol18Unknown)
+	case Protocol18ObjectArray:
+		length, err := readLengthProtocol18(buf, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]interface{}, length)
+		for i := range array {
+			if array[i], err = decodeTypedProtocol18(buf); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case Protocol18EventData:
+		var event EventData
+		if err := binary.Read(buf, binary.BigEndian, &event.Code); err != nil {
+			return nil, err
+		}
+
+		params, err := decodeParameterTableProtocol18(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Parameters = params
+		return event, nil
+	case Protocol18OperationRequest:
+		var request OperationRequestData
+		if err := binary.Read(buf, binary.BigEndian, &request.OperationCode); err != nil {
+			return nil, err
+		}
+
+		params, err := decodeParameterTableProtocol18(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Parameters = params
+		return request, nil
+	case Protocol18OperationResponse:
+		var response OperationResponseData
+		if err := binary.Read(buf, binary.BigEndian, &response.OperationCode); err != nil {
+			return nil, err
+		}
+
+		if err := binary.Read(buf, binary.LittleEndian, &response.OperationResponseCode); err != nil {
+			return nil, err
+		}
+
+		debug, err := decodeTypedProtocol18(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		if debugString, ok := debug.(string); ok {
+			response.OperationDebugString = debugString
+		}
+
+		params, err := decodeParameterTableProtocol18(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		response.Parameters = params
+		return response, nil
+	case Protocol18Array:
+		return decodeArrayProtocol18(buf)
+	case Protocol18BooleanArray:
+		length, err := readCompressedUint32(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		packed := (uint64(length) + 7) / 8
+		if packed > uint64(buf.Len()) {
+			return nil, fmt.Errorf("%w: %d booleans, %d bytes left", protocol.LengthOutOfRange, length, buf.Len())
+		}
+
+		if err := buf.charge(uint64(length)); err != nil {
+			return nil, err
+		}
+
+		bits := make([]byte, packed)
+		if _, err := io.ReadFull(buf, bits); err != nil {
+			return nil, err
+		}
+
+		array := make([]bool, length)
+		for i := range array {
+			array[i] = bits[i/8]&(1<<(i%8)) != 0
+		}
+		return array, nil
+	case Protocol18ByteArray:
+		return decodeByteArrayProtocol18(buf)
+	case Protocol18ShortArray:
+		length, err := readLengthProtocol18(buf, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]int16, length)
+		err = binary.Read(buf, binary.LittleEndian, array)
+		return array, err
+	case Protocol18FloatArray:
+		length, err := readLengthProtocol18(buf, 4)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]float32, length)
+		err = binary.Read(buf, binary.LittleEndian, array)
+		return array, err
+	case Protocol18DoubleArray:
+		length, err := readLengthProtocol18(buf, 8)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]float64, length)
+		err = binary.Read(buf, binary.LittleEndian, array)
+		return array, err
+	case Protocol18StringArray:
+		length, err := readLengthProtocol18(buf, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]string, length)
+		for i := range array {
+			if array[i], err = decodeStringProtocol18(buf); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case Protocol18CompressedIntArray:
+		length, err := readLengthProtocol18(buf, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]int32, length)
+		for i := range array {
+			value, err := readCompressedUint64(buf)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = int32(zigzagDecode(value))
+		}
+		return array, nil
+	case Protocol18CompressedLongArray:
+		length, err := readLengthProtocol18(buf, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]int64, length)
+		for i := range array {
+			value, err := readCompressedUint64(buf)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = zigzagDecode(value)
+		}
+		return array, nil
+	case Protocol18CustomTypeArray:
+		length, err := readLengthProtocol18(buf, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		var customType uint8
+		if err := binary.Read(buf, binary.BigEndian, &customType); err != nil {
+			return nil, err
+		}
+
+		array := make([]CustomData, length)
+		for i := range array {
+			if array[i], err = decodeCustomProtocol18(buf, customType); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case Protocol18DictionaryArray:
+		keyTypeCode, valueTypeCode, err := decodeDictionaryTypeCodesProtocol18(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		length, err := readLengthProtocol18(buf, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]map[interface{}]interface{}, length)
+		for i := range array {
+			if array[i], err = decodeDictionaryProtocol18(buf, keyTypeCode, valueTypeCode); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case Protocol18HashtableArray:
+		length, err := readLengthProtocol18(buf, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]map[interface{}]interface{}, length)
+		for i := range array {
+			if array[i], err = decodeDictionaryProtocol18(buf, Protocol18Unknown, Protocol18Unknown); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("ERROR - Invalid protocol18 type of %v", typeCode)
+	}
+}
+
+// decodeArrayProtocol18 Reads an array whose elements share one type code
+func decodeArrayProtocol18(buf *protocol18Buffer) (interface{}, error) {
+	length, err := readLengthProtocol18(buf, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var elementType uint8
+	if err := binary.Read(buf, binary.BigEndian, &elementType); err != nil {
+		return nil, err
+	}
+
+	if elementType == Protocol18ByteArray {
+		array := make([][]int8, length)
+		for i := range array {
+			if array[i], err = decodeByteArrayProtocol18(buf); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	}
+
+	array := make([]interface{}, length)
+	for i := range array {
+		if array[i], err = decodeTypeProtocol18(buf, elementType); err != nil {
+			return nil, err
+		}
+	}
+	return array, nil
+}
+
+func decodeByteArrayProtocol18(buf *protocol18Buffer) ([]int8, error) {
+	length, err := readLengthProtocol18(buf, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	array := make([]int8, length)
+	err = binary.Read(buf, binary.BigEndian, array)
+	return array, err
+}
+
+func decodeStringProtocol18(buf *protocol18Buffer) (string, error) {
+	length, err := readLengthProtocol18(buf, 1)
+	if err != nil {
+		return "", err
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(buf, data); err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func decodeCustomProtocol18(buf *protocol18Buffer, customType uint8) (CustomData, error) {
+	length, err := readLengthProtocol18(buf, 1)
+	if err != nil {
+		return CustomData{}, err
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(buf, data); err != nil {
+		return CustomData{}, err
+	}
+
+	return CustomData{TypeCode: customType, Data: data}, nil
+}
+
+func decodeDictionaryTypeCodesProtocol18(buf *protocol18Buffer) (keyTypeCode uint8, valueTypeCode uint8, err error) {
+	if err = binary.Read(buf, binary.BigEndian, &keyTypeCode); err != nil {
+		return
+	}
+
+	err = binary.Read(buf, binary.BigEndian, &valueTypeCode)
+	return
+}
+
+func decodeDictionaryProtocol18(buf *protocol18Buffer, keyTypeCode uint8, valueTypeCode uint8) (map[interface{}]interface{}, error) {
+	length, err := readLengthProtocol18(buf, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	dictionary := make(map[interface{}]interface{})
+
+	for i := uint32(0); i < length; i++ {
+		var key interface{}
+		if keyTypeCode == Protocol18Unknown {
+			key, err = decodeTypedProtocol18(buf)
+		} else {
+			key, err = decodeTypeProtocol18(buf, keyTypeCode)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			return nil, fmt.Errorf("%w: dictionary key of type %T", protocol.MalformedData, key)
+		}
+
+		var value interface{}
+		if valueTypeCode == Protocol18Unknown {
+			value, err = decodeTypedProtocol18(buf)
+		} else {
+			value, err = decodeTypeProtocol18(buf, valueTypeCode)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		dictionary[key] = value
+	}
+
+	return dictionary, nil
+}
+
+// readLengthProtocol18 Reads a compressed length and makes sure elements of at least
+// size bytes each can still be read from the buffer
+func readLengthProtocol18(buf *protocol18Buffer, size uint64) (uint32, error) {
+	length, err := readCompressedUint32(buf)
+	if err != nil {
+		return 0, err
+	}
+
+	if uint64(length)*size > uint64(buf.Len()) {
+		return 0, fmt.Errorf("%w: %d elements, %d bytes left", protocol.LengthOutOfRange, length, buf.Len())
+	}
+
+	if err := buf.charge(uint64(length)); err != nil {
+		return 0, err
+	}
+
+	return length, nil
+}
+
+func readCompressedUint32(buf *protocol18Buffer) (uint32, error) {
+	value, err := readCompressedUint64(buf)
+	if err != nil {
+		return 0, err
+	}
+
+	if value > math.MaxUint32 {
+		return 0, fmt.Errorf("%w: compressed integer overflows 32 bits", protocol.MalformedData)
+	}
+
+	return uint32(value), nil
+}
+
+func readCompressedUint64(buf *protocol18Buffer) (uint64, error) {
+	value, err := binary.ReadUvarint(buf)
+	if err != nil {
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, fmt.Errorf("%w: %v", protocol.MalformedData, err)
+	}
+
+	return value, nil
+}
+
+func zigzagDecode(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
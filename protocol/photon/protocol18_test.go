@@ -0,0 +1,166 @@
+package photon
+
+import (
+	"M00DSWINGS/protocol"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// rawLittle Concatenates little endian encodings of the given values
+func rawLittle(values ...any) []byte {
+	var buf bytes.Buffer
+
+	for _, value := range values {
+		switch v := value.(type) {
+		case []byte:
+			buf.Write(v)
+		default:
+			_ = binary.Write(&buf, binary.LittleEndian, v)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecodeTypeProtocol18(t *testing.T) {
+	tests := []struct {
+		name     string
+		typeCode uint8
+		data     []byte
+		want     interface{}
+	}{
+		{"null", Protocol18Null, nil, nil},
+		{"bool", Protocol18Boolean, []byte{1}, true},
+		{"bool false", Protocol18BooleanFalse, nil, false},
+		{"byte", Protocol18Byte, []byte{0xff}, int8(-1)},
+		{"short", Protocol18Short, rawLittle(int16(-300)), int16(-300)},
+		{"float", Protocol18Float, rawLittle(float32(1.5)), float32(1.5)},
+		{"double", Protocol18Double, rawLittle(2.25), 2.25},
+		{"string", Protocol18String, append([]byte{3}, "abc"...), "abc"},
+		{"compressed int", Protocol18CompressedInt, []byte{0x03}, int32(-2)},
+		{"compressed int multi byte", Protocol18CompressedInt, []byte{0xac, 0x02}, int32(150)},
+		{"compressed long", Protocol18CompressedLong, []byte{0x01}, int64(-1)},
+		{"int1", Protocol18Int1, []byte{200}, int32(200)},
+		{"int1 negative", Protocol18Int1Negative, []byte{200}, int32(-200)},
+		{"int2", Protocol18Int2, rawLittle(uint16(1000)), int32(1000)},
+		{"long2 negative", Protocol18Long2Negative, rawLittle(uint16(1000)), int64(-1000)},
+		{"int zero", Protocol18IntZero, nil, int32(0)},
+		{"custom", Protocol18Custom, []byte{7, 2, 0xaa, 0xbb}, CustomData{TypeCode: 7, Data: []byte{0xaa, 0xbb}}},
+		{"custom slim", Protocol18CustomTypeSlim + 7, []byte{1, 0xaa}, CustomData{TypeCode: 7, Data: []byte{0xaa}}},
+		{"byte array", Protocol18ByteArray, []byte{2, 1, 0xff}, []int8{1, -1}},
+		{"short array", Protocol18ShortArray, append([]byte{2}, rawLittle(int16(1), int16(-1))...), []int16{1, -1}},
+		{"string array", Protocol18StringArray, []byte{2, 1, 'a', 0}, []string{"a", ""}},
+		{"boolean array", Protocol18BooleanArray, []byte{3, 0x05}, []bool{true, false, true}},
+		{"compressed int array", Protocol18CompressedIntArray, []byte{2, 0x02, 0x01}, []int32{1, -1}},
+		{
+			"array of byte arrays", Protocol18Array,
+			[]byte{2, Protocol18ByteArray, 1, 5, 0},
+			[][]int8{{5}, {}},
+		},
+		{
+			"array of strings", Protocol18Array,
+			[]byte{1, Protocol18String, 1, 'x'},
+			[]interface{}{"x"},
+		},
+		{
+			"object array", Protocol18ObjectArray,
+			[]byte{2, Protocol18Int1, 5, Protocol18Null},
+			[]interface{}{int32(5), nil},
+		},
+		{
+			"dictionary", Protocol18Dictionary,
+			[]byte{Protocol18String, Protocol18Unknown, 1, 1, 'a', Protocol18BooleanTrue},
+			map[interface{}]interface{}{"a": true},
+		},
+		{
+			"hashtable", Protocol18Hashtable,
+			[]byte{1, Protocol18Byte, 1, Protocol18String, 1, 'v'},
+			map[interface{}]interface{}{int8(1): "v"},
+		},
+		{
+			"event", Protocol18EventData,
+			[]byte{9, 1, 0, Protocol18Int1, 5},
+			EventData{Code: 9, Parameters: ReliableMessageParamaters{0: int32(5)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := newProtocol18Buffer(tt.data)
+
+			got, err := decodeTypeProtocol18(buf, tt.typeCode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+
+			if buf.Len() != 0 {
+				t.Errorf("%d bytes left unread", buf.Len())
+			}
+		})
+	}
+}
+
+func TestAutoDeserializer(t *testing.T) {
+	// The same event once per protocol: a string parameter 1 and the event type 229 in parameter 252
+	protocol16 := Command{Type: SendReliableType, Data: raw(
+		uint8(0xf3), uint8(EventDataType), uint8(1), uint16(2),
+		uint8(1), uint8(StringType), "Triky313",
+		uint8(252), uint8(Int16Type), int16(229),
+	)}
+	protocol18 := Command{Type: SendReliableType, Data: append([]byte{
+		0xf3, EventDataType, 1, 2,
+		1, Protocol18String, 8}, append([]byte("Triky313"),
+		252, Protocol18Short, 229, 0)...,
+	)}
+
+	want := ReliableMessageParamaters{1: "Triky313", 252: int16(229)}
+
+	auto := NewAutoDeserializer(Protocol16{}, Protocol18{})
+
+	for i, command := range []Command{protocol16, protocol18, protocol18} {
+		_, params, err := auto.Deserialize(command)
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("message %d: got %#v, want %#v", i, params, want)
+		}
+	}
+
+	if _, ok := auto.Current().(Protocol18); !ok {
+		t.Errorf("auto deserializer stayed on %T", auto.Current())
+	}
+}
+
+// nestedZeroArrays Builds an event whose single parameter is an array of arrays of IntZero. Every inner
+// array claims most of the buffer while its elements take no bytes, which once made decoding a 32 KB
+// packet take the better part of a minute.
+func nestedZeroArrays() []byte {
+	const inner, outer, size = 16000, 4000, 32 * 1024
+
+	data := []byte{0xf3, EventDataType, 1, 1, 1, Protocol18Array}
+	data = binary.AppendUvarint(data, outer)
+	data = append(data, Protocol18Array)
+
+	for i := 0; i < outer; i++ {
+		data = binary.AppendUvarint(data, inner)
+		data = append(data, Protocol18IntZero)
+	}
+
+	return append(data, make([]byte, size-len(data))...)
+}
+
+func TestProtocol18BoundsNestedZeroArrays(t *testing.T) {
+	_, _, err := Protocol18{}.Deserialize(Command{Type: SendReliableType, Data: nestedZeroArrays()})
+	if !errors.Is(err, protocol.LengthOutOfRange) {
+		t.Errorf("got %v, want %v", err, protocol.LengthOutOfRange)
+	}
+}
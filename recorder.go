@@ -0,0 +1,201 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Recorder Writes raw packets into pcapng segments inside a directory. A segment is rotated once
+// it grows over maxSize bytes or has been open for maxAge, finished segments are gzip compressed.
+type Recorder struct {
+	dir      string
+	maxSize  int64
+	maxAge   time.Duration
+	linkType layers.LinkType
+	mx       *sync.Mutex
+	wg       *sync.WaitGroup
+
+	file    *os.File
+	writer  *pcapgo.NgWriter
+	written int64
+	opened  time.Time
+}
+
+func NewRecorder(dir string, maxSize int64, maxAge time.Duration) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		dir:      dir,
+		maxSize:  maxSize,
+		maxAge:   maxAge,
+		linkType: layers.LinkTypeEthernet,
+		mx:       new(sync.Mutex),
+		wg:       new(sync.WaitGroup),
+	}, nil
+}
+
+// SetLinkType Sets the link type used for segments opened from now on.
+func (r *Recorder) SetLinkType(linkType layers.LinkType) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.linkType = linkType
+}
+
+func (r *Recorder) WritePacket(ci gopacket.CaptureInfo, data []byte) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.writer != nil && r.shouldRotate(ci.Timestamp) {
+		if err := r.closeSegment(); err != nil {
+			return err
+		}
+	}
+
+	if r.writer == nil {
+		if err := r.openSegment(ci.Timestamp); err != nil {
+			return err
+		}
+	}
+
+	ci.InterfaceIndex = 0
+
+	if err := r.writer.WritePacket(ci, data); err != nil {
+		return err
+	}
+
+	// The live capture is usually stopped by killing the process, so don't keep packets buffered
+	if err := r.writer.Flush(); err != nil {
+		return err
+	}
+
+	r.written += int64(len(data))
+
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	var err error
+	if r.writer != nil {
+		err = r.closeSegment()
+	}
+
+	r.wg.Wait()
+
+	return err
+}
+
+func (r *Recorder) shouldRotate(now time.Time) bool {
+	if r.maxSize > 0 && r.written >= r.maxSize {
+		return true
+	}
+
+	if r.maxAge > 0 && now.Sub(r.opened) >= r.maxAge {
+		return true
+	}
+
+	return false
+}
+
+func (r *Recorder) openSegment(now time.Time) error {
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	name := filepath.Join(r.dir, fmt.Sprintf("capture-%s.pcapng", now.UTC().Format("20060102-150405.000")))
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	writer, err := pcapgo.NewNgWriter(file, r.linkType)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	r.file = file
+	r.writer = writer
+	r.written = 0
+	r.opened = now
+
+	return nil
+}
+
+func (r *Recorder) closeSegment() error {
+	file := r.file
+
+	flushErr := r.writer.Flush()
+	r.file = nil
+	r.writer = nil
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if flushErr != nil {
+		return flushErr
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		if err := compressSegment(file.Name()); err != nil {
+			log.Printf("Failed to compress capture segment %s: %v", file.Name(), err)
+		}
+	}()
+
+	return nil
+}
+
+func compressSegment(name string) error {
+	if err := gzipFile(name, name+".gz"); err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
+
+func gzipFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
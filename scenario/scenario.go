@@ -0,0 +1,152 @@
+// Package scenario Scripts game sessions as a list of packets and plays them as Photon commands, the simulator
+// and the logger share it.
+package scenario
+
+import (
+	"M00DSWINGS/protocol/packets"
+	"M00DSWINGS/protocol/photon"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Scenario A scripted game session, steps are sent in order. YAML and JSON files are both accepted.
+type Scenario struct {
+	Start time.Time `yaml:"start"`
+	Steps []Step    `yaml:"steps"`
+}
+
+// Step A single packet, Packet names a struct of the packets package and Fields sets its fields by name.
+type Step struct {
+	Delay  time.Duration          `yaml:"delay"`
+	Packet string                 `yaml:"packet"`
+	Fields map[string]interface{} `yaml:"fields"`
+}
+
+// Emitter Delivers the commands of a session, at is the time the server sent them
+type Emitter interface {
+	Emit(at time.Time, command photon.Command) error
+	Close() error
+}
+
+type definition struct {
+	event bool
+	code  int16
+	typ   reflect.Type
+}
+
+var definitions = make(map[string]definition)
+
+func init() {
+	for _, operation := range append(packets.Operations, packets.Responses...) {
+		typ := reflect.TypeOf(operation.Packet)
+		definitions[typ.Name()] = definition{code: int16(operation.Type), typ: typ}
+	}
+
+	for _, event := range packets.Events {
+		typ := reflect.TypeOf(event.Packet)
+		definitions[typ.Name()] = definition{event: true, code: int16(event.Type), typ: typ}
+	}
+}
+
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var scenario Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if scenario.Start.IsZero() {
+		scenario.Start = time.Now().UTC()
+	}
+
+	return &scenario, nil
+}
+
+// Play Sends every step through the emitter, messages larger than fragmentSize are fragmented. log is called
+// after each step and may be nil.
+func (s *Scenario) Play(emitter Emitter, fragmentSize int, log func(i int, step Step)) error {
+	sequence := uint32(1)
+	at := s.Start
+
+	for i, step := range s.Steps {
+		at = at.Add(step.Delay)
+
+		msg, params, err := step.Message()
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i, err)
+		}
+
+		data, err := photon.EncodeReliableMessage(msg, params)
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i, err)
+		}
+
+		commands := photon.NewReliableCommands(0, sequence, data, fragmentSize)
+		sequence += uint32(len(commands))
+
+		for _, command := range commands {
+			if err := emitter.Emit(at, command); err != nil {
+				return fmt.Errorf("step %d: %w", i, err)
+			}
+		}
+
+		if log != nil {
+			log(i, step)
+		}
+	}
+
+	return nil
+}
+
+// Build Returns a pointer to the packet struct of the step with its fields set
+func (s Step) Build() (interface{}, error) {
+	def, ok := definitions[s.Packet]
+	if !ok {
+		return nil, fmt.Errorf("unknown packet %q", s.Packet)
+	}
+
+	packet := reflect.New(def.typ).Interface()
+
+	// Going through JSON matches field names case-insensitively and parses UUID strings
+	fields, err := json.Marshal(s.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(fields, packet); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Packet, err)
+	}
+
+	return packet, nil
+}
+
+// Message Builds the Photon message of a step
+func (s Step) Message() (photon.ReliableMessage, photon.ReliableMessageParamaters, error) {
+	packet, err := s.Build()
+	if err != nil {
+		return photon.ReliableMessage{}, nil, err
+	}
+
+	params, err := packets.Encode(packet)
+	if err != nil {
+		return photon.ReliableMessage{}, nil, err
+	}
+
+	def := definitions[s.Packet]
+
+	if def.event {
+		params[252] = def.code
+		return photon.ReliableMessage{Type: photon.EventDataType, EventCode: 1}, params, nil
+	}
+
+	params[253] = def.code
+	return photon.ReliableMessage{Type: photon.OperationResponse, OperationCode: 1}, params, nil
+}
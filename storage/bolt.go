@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+var (
+	charactersBucket  = []byte("characters")
+	partyEventsBucket = []byte("party_events")
+	lootChestsBucket  = []byte("loot_chests")
+	grabbedLootBucket = []byte("grabbed_loot")
+	auditBucket       = []byte("audit")
+)
+
+// BoltStore Stores every record as JSON in a bbolt file. Characters are keyed by their ID, everything else by
+// timestamp followed by the bucket sequence, so a time range is a single cursor scan.
+type BoltStore struct {
+	db *bbolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{charactersBucket, partyEventsBucket, lootChestsBucket, grabbedLootBucket, auditBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) SaveCharacter(c Character) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(charactersBucket).Put(c.ID[:], data)
+	})
+}
+
+func (s *BoltStore) SavePartyEvent(e PartyEvent) error {
+	return s.append(partyEventsBucket, e.Timestamp, e)
+}
+
+func (s *BoltStore) SaveLootChest(c LootChest) error {
+	return s.append(lootChestsBucket, c.Timestamp, c)
+}
+
+func (s *BoltStore) SaveGrabbedLoot(l GrabbedLoot) error {
+	return s.append(grabbedLootBucket, l.Timestamp, l)
+}
+
+func (s *BoltStore) SaveAuditEntry(e AuditEntry) error {
+	return s.append(auditBucket, e.Timestamp, e)
+}
+
+func (s *BoltStore) Characters() ([]Character, error) {
+	characters := make([]Character, 0)
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(charactersBucket).ForEach(func(_, v []byte) error {
+			var c Character
+			if err := json.Unmarshal(v, &c); err != nil {
+				return err
+			}
+
+			characters = append(characters, c)
+			return nil
+		})
+	})
+
+	return characters, err
+}
+
+func (s *BoltStore) PartyEvents(from time.Time, to time.Time) ([]PartyEvent, error) {
+	events := make([]PartyEvent, 0)
+
+	err := s.scan(partyEventsBucket, from, to, func(v []byte) error {
+		var e PartyEvent
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+
+		events = append(events, e)
+		return nil
+	})
+
+	return events, err
+}
+
+func (s *BoltStore) LootChests(from time.Time, to time.Time) ([]LootChest, error) {
+	chests := make([]LootChest, 0)
+
+	err := s.scan(lootChestsBucket, from, to, func(v []byte) error {
+		var c LootChest
+		if err := json.Unmarshal(v, &c); err != nil {
+			return err
+		}
+
+		chests = append(chests, c)
+		return nil
+	})
+
+	return chests, err
+}
+
+func (s *BoltStore) GrabbedLoot(from time.Time, to time.Time) ([]GrabbedLoot, error) {
+	loot := make([]GrabbedLoot, 0)
+
+	err := s.scan(grabbedLootBucket, from, to, func(v []byte) error {
+		var l GrabbedLoot
+		if err := json.Unmarshal(v, &l); err != nil {
+			return err
+		}
+
+		loot = append(loot, l)
+		return nil
+	})
+
+	return loot, err
+}
+
+func (s *BoltStore) AuditEntries(from time.Time, to time.Time) ([]AuditEntry, error) {
+	entries := make([]AuditEntry, 0)
+
+	err := s.scan(auditBucket, from, to, func(v []byte) error {
+		var e AuditEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+
+		entries = append(entries, e)
+		return nil
+	})
+
+	return entries, err
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) append(bucket []byte, timestamp time.Time, record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		key := make([]byte, 16)
+		binary.BigEndian.PutUint64(key, uint64(timestamp.UnixNano()))
+		binary.BigEndian.PutUint64(key[8:], seq)
+
+		return b.Put(key, data)
+	})
+}
+
+func (s *BoltStore) scan(bucket []byte, from time.Time, to time.Time, f func(v []byte) error) error {
+	start := make([]byte, 8)
+	binary.BigEndian.PutUint64(start, uint64(from.UnixNano()))
+
+	end := make([]byte, 8)
+	binary.BigEndian.PutUint64(end, uint64(to.UnixNano()))
+
+	return s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+
+		for k, v := c.Seek(start); k != nil && bytes.Compare(k[:8], end) < 0; k, v = c.Next() {
+			if err := f(v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"github.com/google/uuid"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openStore(t *testing.T, path string) *BoltStore {
+	t.Helper()
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestBoltStoreSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.db")
+	store := openStore(t, path)
+
+	char := Character{ID: uuid.New(), Name: "Anna", Guild: "Moodswings", LastSeen: time.Now().UTC()}
+	if err := store.SaveCharacter(char); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving again overwrites instead of adding a second record
+	char.Alliance = "SWING"
+	if err := store.SaveCharacter(char); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store = openStore(t, path)
+	defer store.Close()
+
+	characters, err := store.Characters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(characters) != 1 || characters[0].ID != char.ID || characters[0].Alliance != "SWING" {
+		t.Errorf("characters %+v, want %+v", characters, char)
+	}
+}
+
+func TestBoltStoreTimeRange(t *testing.T) {
+	store := openStore(t, filepath.Join(t.TempDir(), "session.db"))
+	defer store.Close()
+
+	start := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	party := uuid.New()
+
+	// Saved out of order and twice within the same instant, both must come back
+	for _, offset := range []time.Duration{2 * time.Hour, 0, time.Hour, time.Hour, 3 * time.Hour} {
+		err := store.SavePartyEvent(PartyEvent{Party: party, Action: "PartyActionJoin", User: uuid.New(), Timestamp: start.Add(offset)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := store.PartyEvents(start.Add(time.Hour), start.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("%d events, want 3", len(events))
+	}
+
+	for i, want := range []time.Duration{time.Hour, time.Hour, 2 * time.Hour} {
+		if !events[i].Timestamp.Equal(start.Add(want)) {
+			t.Errorf("event %d at %v, want %v", i, events[i].Timestamp, start.Add(want))
+		}
+	}
+
+	if err := store.SaveGrabbedLoot(GrabbedLoot{LootedFrom: "Bob", LootedBy: "Anna", ItemIndex: 1234, Quantity: 2, Cluster: "4000", Timestamp: start}); err != nil {
+		t.Fatal(err)
+	}
+
+	if loot, err := store.GrabbedLoot(start, start.Add(time.Minute)); err != nil || len(loot) != 1 || loot[0].ItemIndex != 1234 || loot[0].Cluster != "4000" {
+		t.Errorf("grabbed loot %+v, %v", loot, err)
+	}
+
+	if chests, err := store.LootChests(start, start.Add(time.Minute)); err != nil || len(chests) != 0 {
+		t.Errorf("loot chests %+v, %v", chests, err)
+	}
+}
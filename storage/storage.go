@@ -0,0 +1,92 @@
+// Package storage Persists what the logger learns about a session, so past sessions can be queried without the
+// websocket server.
+package storage
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Store A pluggable persistence backend, time based queries return the records with from <= Timestamp < to in
+// the order they were saved.
+type Store interface {
+	SaveCharacter(c Character) error
+	SavePartyEvent(e PartyEvent) error
+	SaveLootChest(c LootChest) error
+	SaveGrabbedLoot(l GrabbedLoot) error
+	SaveAuditEntry(e AuditEntry) error
+
+	Characters() ([]Character, error)
+	PartyEvents(from time.Time, to time.Time) ([]PartyEvent, error)
+	LootChests(from time.Time, to time.Time) ([]LootChest, error)
+	GrabbedLoot(from time.Time, to time.Time) ([]GrabbedLoot, error)
+	AuditEntries(from time.Time, to time.Time) ([]AuditEntry, error)
+
+	Close() error
+}
+
+type Character struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Guild    string    `json:"guild"`
+	Alliance string    `json:"alliance"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// PartyEvent A party History entry, Party identifies the party across leader changes
+type PartyEvent struct {
+	Party     uuid.UUID `json:"party"`
+	Leader    uuid.UUID `json:"leader"`
+	Action    string    `json:"action"`
+	User      uuid.UUID `json:"user"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// LootChest A chest or corpse, saved when it despawns. Timestamp is when it spawned.
+type LootChest struct {
+	ID        int        `json:"id"`
+	Owner     string     `json:"owner"`
+	Kind      string     `json:"kind"`
+	Cluster   string     `json:"cluster,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+	Despawned time.Time  `json:"despawned"`
+	Contents  []LootItem `json:"contents,omitempty"`
+	Openers   []string   `json:"openers,omitempty"`
+	Takes     []LootTake `json:"takes,omitempty"`
+}
+
+type LootItem struct {
+	ItemIndex int `json:"itemIndex"`
+	Quantity  int `json:"quantity"`
+}
+
+type LootTake struct {
+	Looter    string    `json:"looter"`
+	Silver    bool      `json:"silver"`
+	ItemIndex int       `json:"itemIndex"`
+	Quantity  int       `json:"quantity"`
+	At        time.Time `json:"at"`
+}
+
+type GrabbedLoot struct {
+	LootedFrom string    `json:"lootedFrom"`
+	LootedBy   string    `json:"lootedBy"`
+	IsSilver   bool      `json:"isSilver"`
+	ItemIndex  int       `json:"itemIndex"`
+	ItemName   string    `json:"itemName,omitempty"`
+	Quantity   int       `json:"quantity"`
+	Value      int64     `json:"value"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// AuditEntry A join of the party audit trail with the warnings it raised
+type AuditEntry struct {
+	Kind       string    `json:"kind"`
+	PlayerName string    `json:"playerName"`
+	Guild      string    `json:"guild,omitempty"`
+	Alliance   string    `json:"alliance,omitempty"`
+	Warnings   []string  `json:"warnings,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
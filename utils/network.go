@@ -67,7 +67,7 @@ func FindDevice(device string) (pcap.Interface, error) {
 	}
 
 	for _, d := range devices {
-		if d.Description == device {
+		if d.Description == device || d.Name == device {
 			return d, nil
 		}
 	}